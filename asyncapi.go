@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	asyncAPIFile string
+	wsInterval   = time.Second
+)
+
+// AsyncAPI is the part of an AsyncAPI 2.x or 3.0 document portblock mocks:
+// channels, what flows over them, and which messages get replies
+type AsyncAPI struct {
+	Version  string
+	Title    string
+	Channels []*asyncChannel
+	routes   *routeTable
+}
+
+type asyncChannel struct {
+	address  string
+	inbound  []*asyncMessage // messages clients send
+	outbound []*asyncMessage // messages the server publishes
+	interval time.Duration   // x-portblock-interval, 0 = --ws-interval
+	// scheduled are the outbound messages sent on a timer. in 2.x, a channel
+	// that also accepts messages uses its outbound ones as replies instead
+	scheduled []*asyncMessage
+}
+
+type asyncMessage struct {
+	name    string
+	schema  *openapi3.SchemaRef
+	example interface{}
+	replies []*asyncMessage
+}
+
+// isAsyncAPIFile reports whether a spec file is an AsyncAPI document
+func isAsyncAPIFile(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var head struct {
+		AsyncAPI string `yaml:"asyncapi"`
+	}
+	return yaml.Unmarshal(data, &head) == nil && head.AsyncAPI != ""
+}
+
+func loadAsyncAPI(path string) (*AsyncAPI, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var root map[string]interface{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	version, _ := root["asyncapi"].(string)
+	if version == "" {
+		return nil, fmt.Errorf("%s is not an AsyncAPI document (missing asyncapi version)", path)
+	}
+
+	api := &AsyncAPI{Version: version}
+	if info, ok := root["info"].(map[string]interface{}); ok {
+		api.Title, _ = info["title"].(string)
+	}
+	r := &asyncResolver{root: root}
+	if strings.HasPrefix(version, "2.") {
+		api.Channels = r.channelsV2()
+	} else {
+		api.Channels = r.channelsV3()
+	}
+	sort.Slice(api.Channels, func(i, j int) bool { return api.Channels[i].address < api.Channels[j].address })
+	api.routes = newChannelTable(api.Channels)
+	return api, nil
+}
+
+// channel finds the channel serving a request path; addresses may have {params}
+func (a *AsyncAPI) channel(reqPath string) *asyncChannel {
+	if a == nil || a.routes == nil {
+		return nil
+	}
+	if node, _ := a.routes.lookup(reqPath, ""); node != nil {
+		return node.channel
+	}
+	return nil
+}
+
+// asyncResolver inlines local $refs while walking the document
+type asyncResolver struct {
+	root map[string]interface{}
+}
+
+// maxRefDepth stops recursive schemas from being inlined forever
+const maxRefDepth = 32
+
+func (r *asyncResolver) resolve(node interface{}, depth int) interface{} {
+	if depth > maxRefDepth {
+		return map[string]interface{}{}
+	}
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			return r.resolve(r.lookup(ref), depth+1)
+		}
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			out[k] = r.resolve(val, depth)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = r.resolve(val, depth)
+		}
+		return out
+	}
+	return node
+}
+
+func (r *asyncResolver) lookup(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return map[string]interface{}{}
+	}
+	var node interface{} = r.root
+	for _, tok := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return map[string]interface{}{}
+		}
+		node = m[tok]
+	}
+	return node
+}
+
+// refName is the last segment of a $ref, used as the message name
+func refName(node interface{}) string {
+	if m, ok := node.(map[string]interface{}); ok {
+		if ref, ok := m["$ref"].(string); ok {
+			return ref[strings.LastIndex(ref, "/")+1:]
+		}
+	}
+	return ""
+}
+
+// message converts a message object: payload schema, name and first example
+func (r *asyncResolver) message(raw interface{}, fallbackName string) *asyncMessage {
+	name := refName(raw)
+	if name == "" {
+		name = fallbackName
+	}
+	m, _ := r.resolve(raw, 0).(map[string]interface{})
+	if m == nil {
+		return nil
+	}
+	msg := &asyncMessage{name: name}
+	if n, ok := m["name"].(string); ok && n != "" {
+		msg.name = n
+	}
+	if payload, ok := m["payload"]; ok {
+		// AsyncAPI 3 can wrap the schema in {schemaFormat, schema}
+		if pm, ok := payload.(map[string]interface{}); ok {
+			if inner, ok := pm["schema"]; ok && pm["schemaFormat"] != nil {
+				payload = inner
+			}
+		}
+		msg.schema = toOpenAPISchema(payload)
+	}
+	if examples, ok := m["examples"].([]interface{}); ok && len(examples) > 0 {
+		if ex, ok := examples[0].(map[string]interface{}); ok {
+			msg.example = ex["payload"]
+		}
+	}
+	return msg
+}
+
+// messagesV2 reads an operation's message, which may be a oneOf list
+func (r *asyncResolver) messagesV2(op map[string]interface{}) []*asyncMessage {
+	raw, ok := op["message"]
+	if !ok {
+		return nil
+	}
+	name, _ := op["operationId"].(string)
+	if m, ok := raw.(map[string]interface{}); ok {
+		if list, ok := m["oneOf"].([]interface{}); ok {
+			var msgs []*asyncMessage
+			for i, item := range list {
+				if msg := r.message(item, fmt.Sprintf("%s%d", name, i+1)); msg != nil {
+					msgs = append(msgs, msg)
+				}
+			}
+			return msgs
+		}
+	}
+	if msg := r.message(raw, name); msg != nil {
+		return []*asyncMessage{msg}
+	}
+	return nil
+}
+
+// channelsV2: "subscribe" is what clients receive (we publish), "publish" is
+// what clients send. a channel with both answers each inbound message with
+// one of its outbound ones
+func (r *asyncResolver) channelsV2() []*asyncChannel {
+	channels, _ := r.root["channels"].(map[string]interface{})
+	var result []*asyncChannel
+	for name, raw := range channels {
+		item, _ := r.resolveShallow(raw).(map[string]interface{})
+		ch := &asyncChannel{address: channelAddress(name)}
+		if op, ok := item["subscribe"].(map[string]interface{}); ok {
+			ch.outbound = r.messagesV2(op)
+			ch.interval = extensionInterval(op)
+		}
+		if op, ok := item["publish"].(map[string]interface{}); ok {
+			ch.inbound = r.messagesV2(op)
+			for _, msg := range ch.inbound {
+				msg.replies = ch.outbound
+			}
+		}
+		if d := extensionInterval(item); d > 0 {
+			ch.interval = d
+		}
+		if len(ch.inbound) == 0 || ch.interval > 0 {
+			ch.scheduled = ch.outbound
+		}
+		result = append(result, ch)
+	}
+	return result
+}
+
+// channelsV3: operations with action "send" publish, "receive" accept
+// messages and may declare a reply
+func (r *asyncResolver) channelsV3() []*asyncChannel {
+	channels, _ := r.root["channels"].(map[string]interface{})
+	byID := map[string]*asyncChannel{}
+	var result []*asyncChannel
+	for id, raw := range channels {
+		item, _ := r.resolveShallow(raw).(map[string]interface{})
+		address := id
+		if a, ok := item["address"].(string); ok && a != "" {
+			address = a
+		}
+		ch := &asyncChannel{address: channelAddress(address), interval: extensionInterval(item)}
+		byID[id] = ch
+		result = append(result, ch)
+	}
+
+	operations, _ := r.root["operations"].(map[string]interface{})
+	ids := make([]string, 0, len(operations))
+	for id := range operations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, opID := range ids {
+		op, _ := operations[opID].(map[string]interface{})
+		ch := byID[refName(op["channel"])]
+		if ch == nil {
+			continue
+		}
+		msgs := r.operationMessages(op, ch, opID)
+		switch op["action"] {
+		case "send":
+			ch.outbound = append(ch.outbound, msgs...)
+			ch.scheduled = ch.outbound
+			if d := extensionInterval(op); d > 0 {
+				ch.interval = d
+			}
+		case "receive":
+			var replies []*asyncMessage
+			if reply, ok := r.resolveShallow(op["reply"]).(map[string]interface{}); ok {
+				replies = r.messageList(reply["messages"], opID+"Reply")
+				if len(replies) == 0 {
+					if replyCh := byID[refName(reply["channel"])]; replyCh != nil {
+						replies = r.channelMessages(replyCh.address)
+					}
+				}
+			}
+			for _, msg := range msgs {
+				msg.replies = replies
+			}
+			ch.inbound = append(ch.inbound, msgs...)
+		}
+	}
+	return result
+}
+
+// resolveShallow follows a $ref without inlining everything below it
+func (r *asyncResolver) resolveShallow(node interface{}) interface{} {
+	if m, ok := node.(map[string]interface{}); ok {
+		if ref, ok := m["$ref"].(string); ok {
+			return r.lookup(ref)
+		}
+	}
+	return node
+}
+
+func (r *asyncResolver) messageList(raw interface{}, fallback string) []*asyncMessage {
+	list, _ := raw.([]interface{})
+	var msgs []*asyncMessage
+	for i, item := range list {
+		if msg := r.message(item, fmt.Sprintf("%s%d", fallback, i+1)); msg != nil {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+// operationMessages are the operation's messages, or all of its channel's
+func (r *asyncResolver) operationMessages(op map[string]interface{}, ch *asyncChannel, opID string) []*asyncMessage {
+	if msgs := r.messageList(op["messages"], opID); len(msgs) > 0 {
+		return msgs
+	}
+	return r.channelMessages(ch.address)
+}
+
+func (r *asyncResolver) channelMessages(address string) []*asyncMessage {
+	channels, _ := r.root["channels"].(map[string]interface{})
+	for id, raw := range channels {
+		item, _ := r.resolveShallow(raw).(map[string]interface{})
+		a, _ := item["address"].(string)
+		if a == "" {
+			a = id
+		}
+		if channelAddress(a) != address {
+			continue
+		}
+		messages, _ := item["messages"].(map[string]interface{})
+		names := make([]string, 0, len(messages))
+		for name := range messages {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var msgs []*asyncMessage
+		for _, name := range names {
+			if msg := r.message(messages[name], name); msg != nil {
+				msgs = append(msgs, msg)
+			}
+		}
+		return msgs
+	}
+	return nil
+}
+
+func channelAddress(name string) string {
+	return "/" + strings.TrimPrefix(name, "/")
+}
+
+func extensionInterval(m map[string]interface{}) time.Duration {
+	if v, ok := m["x-portblock-interval"].(string); ok {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// toOpenAPISchema reads a JSON Schema into kin-openapi's schema type, which
+// covers the keywords generation and validation need
+func toOpenAPISchema(node interface{}) *openapi3.SchemaRef {
+	data, err := json.Marshal(node)
+	if err != nil {
+		return nil
+	}
+	schema := &openapi3.Schema{}
+	if err := json.Unmarshal(data, schema); err != nil {
+		return nil
+	}
+	return openapi3.NewSchemaRef("", schema)
+}
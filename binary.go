@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// isBinaryMediaType reports whether a declared response media type is served
+// as raw bytes rather than serialized structured data
+func isBinaryMediaType(mt string) bool {
+	mt = strings.ToLower(mt)
+	switch {
+	case strings.HasPrefix(mt, "image/"), strings.HasPrefix(mt, "audio/"), strings.HasPrefix(mt, "video/"):
+		return true
+	case mt == "application/octet-stream", mt == "application/pdf", mt == "application/zip", mt == "multipart/mixed":
+		return true
+	}
+	return false
+}
+
+// successResponse returns the first declared 2xx (or default) response of an operation
+func successResponse(op *openapi3.Operation) (int, *openapi3.Response) {
+	if op == nil || op.Responses == nil {
+		return 0, nil
+	}
+	for _, code := range []string{"200", "201", "202", "206"} {
+		if resp := op.Responses.Value(code); resp != nil && resp.Value != nil {
+			c, _ := strconv.Atoi(code)
+			return c, resp.Value
+		}
+	}
+	if resp := op.Responses.Default(); resp != nil && resp.Value != nil {
+		return 200, resp.Value
+	}
+	return 0, nil
+}
+
+// mediaTypeMatches matches an Accept entry (which may be a wildcard like image/*)
+// against a declared media type (which may also be a wildcard)
+func mediaTypeMatches(want, declared string) bool {
+	declared = strings.ToLower(declared)
+	if want == declared || want == "*/*" {
+		return true
+	}
+	wantType, wantSub, _ := strings.Cut(want, "/")
+	declType, declSub, _ := strings.Cut(declared, "/")
+	if wantType != declType {
+		return false
+	}
+	return wantSub == "*" || declSub == "*"
+}
+
+// handleBinary writes a generated payload for a binary media type
+func (s *MockServer) handleBinary(w http.ResponseWriter, r *http.Request, op *openapi3.Operation, mediaType string) int {
+	code, resp := successResponse(op)
+	var media *openapi3.MediaType
+	if resp != nil {
+		media = resp.Content.Get(mediaType)
+	}
+	rng := seededRng(s.seed, r.URL.Path+mediaType)
+
+	// a wildcard like image/* is served as PNG
+	contentType := mediaType
+	if strings.HasSuffix(contentType, "/*") {
+		contentType = strings.TrimSuffix(contentType, "*") + "png"
+		if !strings.HasPrefix(contentType, "image/") {
+			contentType = "application/octet-stream"
+		}
+	}
+
+	var body []byte
+	switch {
+	case contentType == "multipart/mixed":
+		var boundary string
+		body, boundary = generateMultipartMixed(media, rng)
+		contentType = "multipart/mixed; boundary=" + boundary
+	case strings.HasPrefix(contentType, "image/"):
+		body, contentType = generateImage(contentType, rng)
+	case contentType == "application/pdf":
+		body = generatePDF(rng)
+	default:
+		body = generateBlob(mediaSchema(media), rng)
+	}
+
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") {
+		disposition = "inline"
+	}
+	if !strings.HasPrefix(contentType, "multipart/") {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, downloadFilename(op, r.URL.Path, contentType)))
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(code)
+	w.Write(body)
+	return code
+}
+
+func mediaSchema(media *openapi3.MediaType) *openapi3.Schema {
+	if media == nil || media.Schema == nil {
+		return nil
+	}
+	return media.Schema.Value
+}
+
+// downloadFilename names the file after the operation id, or the last path segment
+func downloadFilename(op *openapi3.Operation, reqPath, contentType string) string {
+	base := op.OperationID
+	if base == "" {
+		base = path.Base(reqPath)
+	}
+	if base == "" || base == "/" || base == "." {
+		base = "download"
+	}
+	return base + fileExtension(contentType)
+}
+
+func fileExtension(contentType string) string {
+	mt, _, _ := strings.Cut(contentType, ";")
+	switch mt {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/svg+xml":
+		return ".svg"
+	case "application/pdf":
+		return ".pdf"
+	case "application/octet-stream":
+		return ".bin"
+	}
+	if exts, err := mime.ExtensionsByType(mt); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// maxBlobSize caps generated binaries so a huge maxLength can't exhaust memory
+const maxBlobSize = 1 << 20
+
+// generateBlob returns random bytes sized by the schema's minLength/maxLength
+// (default 1 KiB), at most maxBlobSize
+func generateBlob(schema *openapi3.Schema, rng *rand.Rand) []byte {
+	size := 1024
+	if schema != nil {
+		min := int(clampBlobSize(schema.MinLength))
+		max := size
+		if schema.MaxLength != nil {
+			max = int(clampBlobSize(*schema.MaxLength))
+		}
+		if max < min {
+			max = min
+		}
+		size = min
+		if max > min {
+			size = min + rng.Intn(max-min+1)
+		}
+	}
+	buf := make([]byte, size)
+	rng.Read(buf)
+	return buf
+}
+
+func clampBlobSize(n uint64) uint64 {
+	if n > maxBlobSize {
+		return maxBlobSize
+	}
+	return n
+}
+
+// generateBase64 returns base64 for `format: byte` strings
+func generateBase64(schema *openapi3.Schema, rng *rand.Rand) string {
+	size := 16 + rng.Intn(48)
+	if schema.MaxLength != nil {
+		// base64 grows by 4/3, keep the encoded value within maxLength
+		if max := int(*schema.MaxLength) / 4 * 3; max < size {
+			size = max
+		}
+	}
+	buf := make([]byte, size)
+	rng.Read(buf)
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// generateImage draws a small gradient placeholder in the requested format.
+// formats the stdlib can't encode are served as PNG
+func generateImage(contentType string, rng *rand.Rand) ([]byte, string) {
+	if contentType == "image/svg+xml" {
+		c := randomColor(rng)
+		svg := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="64" height="64"><rect width="64" height="64" fill="#%02x%02x%02x"/></svg>`, c.R, c.G, c.B)
+		return []byte(svg), contentType
+	}
+
+	from, to := randomColor(rng), randomColor(rng)
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			t := float64(x+y) / 126
+			img.Set(x, y, color.RGBA{
+				R: uint8(float64(from.R)*(1-t) + float64(to.R)*t),
+				G: uint8(float64(from.G)*(1-t) + float64(to.G)*t),
+				B: uint8(float64(from.B)*(1-t) + float64(to.B)*t),
+				A: 255,
+			})
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	switch contentType {
+	case "image/jpeg":
+		jpeg.Encode(buf, img, nil)
+	case "image/gif":
+		gif.Encode(buf, img, nil)
+	default:
+		png.Encode(buf, img)
+		contentType = "image/png"
+	}
+	return buf.Bytes(), contentType
+}
+
+func randomColor(rng *rand.Rand) color.RGBA {
+	return color.RGBA{R: uint8(rng.Intn(256)), G: uint8(rng.Intn(256)), B: uint8(rng.Intn(256)), A: 255}
+}
+
+// generatePDF builds a minimal, valid one-page PDF with a line of fake text
+func generatePDF(rng *rand.Rand) []byte {
+	faker := gofakeit.New(uint64(rng.Int63()))
+	text := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(faker.Sentence(6))
+	content := fmt.Sprintf("BT /F1 18 Tf 72 720 Td (%s) Tj ET", text)
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// generateMultipartMixed builds one part per schema property, using the
+// declared encoding content types where present
+func generateMultipartMixed(media *openapi3.MediaType, rng *rand.Rand) ([]byte, string) {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	// seeded boundary keeps responses reproducible
+	mw.SetBoundary(fmt.Sprintf("portblock-%016x", rng.Int63()))
+
+	schema := mediaSchema(media)
+	if schema == nil || len(schema.Properties) == 0 {
+		writeMultipartPart(mw, "metadata", "application/json", nil, rng)
+		writeMultipartPart(mw, "file", "application/octet-stream", nil, rng)
+		mw.Close()
+		return buf.Bytes(), mw.Boundary()
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop := schema.Properties[name]
+		partType := ""
+		if enc, ok := media.Encoding[name]; ok && enc != nil && enc.ContentType != "" {
+			partType = strings.TrimSpace(strings.Split(enc.ContentType, ",")[0])
+		}
+		if partType == "" {
+			partType = "text/plain"
+			if prop.Value != nil {
+				if prop.Value.Format == "binary" {
+					partType = "application/octet-stream"
+				} else if prop.Value.Type.Is("object") || prop.Value.Type.Is("array") {
+					partType = "application/json"
+				}
+			}
+		}
+		writeMultipartPart(mw, name, partType, prop, rng)
+	}
+	mw.Close()
+	return buf.Bytes(), mw.Boundary()
+}
+
+func writeMultipartPart(mw *multipart.Writer, name, partType string, prop *openapi3.SchemaRef, rng *rand.Rand) {
+	var body []byte
+	switch {
+	case partType == "application/json":
+		var v interface{} = map[string]interface{}{"name": name}
+		if prop != nil {
+			v = generateFromSchema(prop, rng, 0)
+		}
+		body, _ = json.Marshal(v)
+	case strings.HasPrefix(partType, "image/"):
+		body, partType = generateImage(partType, rng)
+	case partType == "application/pdf":
+		body = generatePDF(rng)
+	case partType == "application/octet-stream":
+		var schema *openapi3.Schema
+		if prop != nil {
+			schema = prop.Value
+		}
+		body = generateBlob(schema, rng)
+	default:
+		v := generateFromSchemaWithName(prop, rng, 0, name, "", nil)
+		if v == nil {
+			v = gofakeit.New(uint64(rng.Int63())).Word()
+		}
+		body = []byte(fmt.Sprintf("%v", v))
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", partType)
+	if isBinaryMediaType(partType) {
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; name=%q; filename=%q", name, name+fileExtension(partType)))
+	} else {
+		header.Set("Content-Disposition", fmt.Sprintf("inline; name=%q", name))
+	}
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return
+	}
+	part.Write(body)
+}
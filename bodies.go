@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// filesPrefix is where uploaded files can be downloaded again
+const filesPrefix = "/__portblock/files/"
+
+// maxUploadMemory is how much of a multipart body is buffered in memory
+const maxUploadMemory = 32 << 20
+
+// storedFile is a file uploaded through multipart/form-data
+type storedFile struct {
+	id          string
+	filename    string
+	contentType string
+	data        []byte
+}
+
+// url is the download path stored in place of the file in the resource
+func (f *storedFile) url() string {
+	return filesPrefix + f.id + "/" + url.PathEscape(f.filename)
+}
+
+func (s *Store) PutFile(f *storedFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[f.id] = f
+}
+
+func (s *Store) GetFile(id string) (*storedFile, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.files[id]
+	return f, ok
+}
+
+// --------------- Request Bodies ---------------
+
+// requestMediaType is the request's Content-Type without parameters
+func requestMediaType(r *http.Request) string {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return "application/json"
+	}
+	return mt
+}
+
+// requestBodySchema returns the request body schema for mediaType, falling back to JSON
+func requestBodySchema(op *openapi3.Operation, mediaType string) *openapi3.SchemaRef {
+	if op == nil || op.RequestBody == nil || op.RequestBody.Value == nil {
+		return nil
+	}
+	for _, mt := range []string{mediaType, "application/json"} {
+		if media := op.RequestBody.Value.Content.Get(mt); media != nil && media.Schema != nil {
+			return media.Schema
+		}
+	}
+	return nil
+}
+
+// parseRequestBody decodes a JSON, XML, form or multipart body into an object.
+// uploaded files are returned separately and replaced by their download URL
+func parseRequestBody(data []byte, contentType string, schema *openapi3.SchemaRef) (map[string]interface{}, []*storedFile, bool) {
+	mt, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = "application/json"
+	}
+	switch mt {
+	case "application/xml", "text/xml":
+		v, err := decodeXMLBody(data, schema)
+		if err != nil {
+			return nil, nil, false
+		}
+		m, ok := v.(map[string]interface{})
+		return m, nil, ok
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(data))
+		if err != nil {
+			return nil, nil, false
+		}
+		return formValues(values, schema), nil, true
+	case "multipart/form-data":
+		return parseMultipartForm(data, params["boundary"], schema)
+	default:
+		var m map[string]interface{}
+		if json.Unmarshal(data, &m) != nil {
+			return nil, nil, false
+		}
+		return m, nil, m != nil
+	}
+}
+
+// decodeRequestBody reads the body handlers store; unparseable bodies become an
+// empty object. uploaded files are kept so they can be downloaded later
+func (s *MockServer) decodeRequestBody(r *http.Request, op *openapi3.Operation) map[string]interface{} {
+	if r.Body == nil {
+		return make(map[string]interface{})
+	}
+	data, _ := io.ReadAll(r.Body)
+	body, files, ok := parseRequestBody(data, r.Header.Get("Content-Type"), requestBodySchema(op, requestMediaType(r)))
+	if !ok {
+		return make(map[string]interface{})
+	}
+	for _, f := range files {
+		s.store.PutFile(f)
+	}
+	return body
+}
+
+// formValues turns form fields into typed values: repeated or array fields
+// become arrays, object fields are parsed as JSON
+func formValues(values url.Values, schema *openapi3.SchemaRef) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for name, vals := range values {
+		result[name] = formValue(vals, propertySchema(schema, name))
+	}
+	return result
+}
+
+func propertySchema(schema *openapi3.SchemaRef, name string) *openapi3.Schema {
+	if schema == nil || schema.Value == nil {
+		return nil
+	}
+	return schemaValue(schema.Value.Properties[name])
+}
+
+func formValue(vals []string, schema *openapi3.Schema) interface{} {
+	if schema == nil {
+		if len(vals) == 1 {
+			return vals[0]
+		}
+		arr := make([]interface{}, len(vals))
+		for i, v := range vals {
+			arr[i] = v
+		}
+		return arr
+	}
+	if schema.Type.Is("array") {
+		arr := make([]interface{}, 0, len(vals))
+		for _, v := range vals {
+			arr = append(arr, formValue([]string{v}, schemaValue(schema.Items)))
+		}
+		return arr
+	}
+	if len(vals) == 0 {
+		return nil
+	}
+	if schema.Type.Is("object") {
+		var v interface{}
+		if json.Unmarshal([]byte(vals[0]), &v) == nil {
+			return v
+		}
+	}
+	return parseScalar(vals[0], schema)
+}
+
+// parseMultipartForm reads multipart/form-data. file parts become storedFiles,
+// every other part is treated like a form field
+func parseMultipartForm(data []byte, boundary string, schema *openapi3.SchemaRef) (map[string]interface{}, []*storedFile, bool) {
+	if boundary == "" {
+		return nil, nil, false
+	}
+	form, err := multipart.NewReader(bytes.NewReader(data), boundary).ReadForm(maxUploadMemory)
+	if err != nil {
+		return nil, nil, false
+	}
+	defer form.RemoveAll()
+
+	result := formValues(form.Value, schema)
+
+	var files []*storedFile
+	for name, headers := range form.File {
+		var urls []interface{}
+		for _, fh := range headers {
+			f, err := fh.Open()
+			if err != nil {
+				continue
+			}
+			content, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				continue
+			}
+			ct := fh.Header.Get("Content-Type")
+			if ct == "" {
+				ct = "application/octet-stream"
+			}
+			stored := &storedFile{id: gofakeit.UUID(), filename: fh.Filename, contentType: ct, data: content}
+			files = append(files, stored)
+			urls = append(urls, stored.url())
+		}
+		if len(urls) == 0 {
+			continue
+		}
+		if prop := propertySchema(schema, name); len(urls) > 1 || (prop != nil && prop.Type.Is("array")) {
+			result[name] = urls
+		} else {
+			result[name] = urls[0]
+		}
+	}
+	return result, files, true
+}
+
+// --------------- Uploaded Files ---------------
+
+// serveUpload serves a file from filesPrefix
+func (s *MockServer) serveUpload(w http.ResponseWriter, r *http.Request) int {
+	id, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, filesPrefix), "/")
+	f, ok := s.store.GetFile(id)
+	if !ok {
+		s.writeError(w, r, nil, mockError{status: 404, detail: "file not found"})
+		return 404
+	}
+	writeStoredFile(w, f, 200)
+	return 200
+}
+
+// storedUpload finds the file uploaded into a stored resource's field,
+// so GET /pets/7/photo returns what was posted as the pet's photo
+func (s *MockServer) storedUpload(reqPath string) *storedFile {
+	parent, field := path.Split(strings.TrimRight(reqPath, "/"))
+	parent = strings.TrimRight(parent, "/")
+	resource, id := extractResource(parent), path.Base(parent)
+	if resource == id {
+		return nil
+	}
+	obj, ok := s.store.Get(resource, id)
+	if !ok {
+		return nil
+	}
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	u, ok := m[field].(string)
+	if !ok || !strings.HasPrefix(u, filesPrefix) {
+		return nil
+	}
+	fileID, _, _ := strings.Cut(strings.TrimPrefix(u, filesPrefix), "/")
+	f, _ := s.store.GetFile(fileID)
+	return f
+}
+
+func writeStoredFile(w http.ResponseWriter, f *storedFile, code int) {
+	disposition := "attachment"
+	if strings.HasPrefix(f.contentType, "image/") {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, f.filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(f.data)))
+	w.WriteHeader(code)
+	w.Write(f.data)
+}
+
+// --------------- Text Responses ---------------
+
+// writeText writes strings and scalars as-is; anything structured is JSON
+func writeText(w io.Writer, data interface{}) {
+	switch v := data.(type) {
+	case string:
+		io.WriteString(w, v)
+	case map[string]interface{}, map[string]string, []interface{}:
+		json.NewEncoder(w).Encode(v)
+	default:
+		fmt.Fprintf(w, "%v", v)
+	}
+}
+
+// writeCSV writes a list of objects as a header row plus one row per item.
+// columns follow the item schema's property order; nested values are JSON
+func writeCSV(w io.Writer, data interface{}, schema *openapi3.SchemaRef, order propertyOrder) {
+	var rows []interface{}
+	itemSchema := schemaValue(schema)
+	switch v := data.(type) {
+	case []interface{}:
+		rows = v
+		if itemSchema != nil {
+			itemSchema = schemaValue(itemSchema.Items)
+		}
+	case string:
+		io.WriteString(w, v)
+		return
+	default:
+		rows = []interface{}{v}
+	}
+
+	var columns []string
+	seen := map[string]bool{}
+	if itemSchema != nil {
+		for _, name := range order.orderedProperties(itemSchema) {
+			columns = append(columns, name)
+			seen[name] = true
+		}
+	}
+	var extra []string
+	for _, row := range rows {
+		if m, ok := row.(map[string]interface{}); ok {
+			for k := range m {
+				if !seen[k] {
+					seen[k] = true
+					extra = append(extra, k)
+				}
+			}
+		}
+	}
+	sort.Strings(extra)
+	columns = append(columns, extra...)
+
+	cw := csv.NewWriter(w)
+	if len(columns) > 0 {
+		cw.Write(columns)
+	}
+	for _, row := range rows {
+		m, ok := row.(map[string]interface{})
+		if !ok {
+			cw.Write([]string{csvCell(row)})
+			continue
+		}
+		record := make([]string, len(columns))
+		for i, col := range columns {
+			record[i] = csvCell(m[col])
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+}
+
+func csvCell(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(val)
+		return string(b)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
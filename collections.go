@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxListSize caps generated collections so a stray Prefer: count can't exhaust memory
+const maxListSize = 100000
+
+var (
+	listSizeFlag    string
+	defaultListSize countRange
+)
+
+// countRange is an inclusive item count range; the zero value means "unset"
+type countRange struct {
+	min, max int
+}
+
+func (c countRange) isSet() bool {
+	return c.max > 0 || c.min > 0
+}
+
+func (c countRange) pick(rng *rand.Rand) int {
+	if c.max <= c.min {
+		return c.min
+	}
+	return c.min + rng.Intn(c.max-c.min+1)
+}
+
+// parseCountRange parses "10" or "5-20"
+func parseCountRange(s string) (countRange, error) {
+	s = strings.TrimSpace(s)
+	lo, hi, isRange := strings.Cut(s, "-")
+	min, err := strconv.Atoi(strings.TrimSpace(lo))
+	if err != nil || min < 0 {
+		return countRange{}, fmt.Errorf("invalid count %q: use N or MIN-MAX", s)
+	}
+	if !isRange {
+		return countRange{min: min, max: min}, nil
+	}
+	max, err := strconv.Atoi(strings.TrimSpace(hi))
+	if err != nil || max < min {
+		return countRange{}, fmt.Errorf("invalid count %q: use N or MIN-MAX", s)
+	}
+	return countRange{min: min, max: max}, nil
+}
+
+// schemaCountRange reads x-portblock-count: a number, "MIN-MAX", or [MIN, MAX]
+func schemaCountRange(schema *openapi3.Schema) (countRange, bool) {
+	raw, ok := schema.Extensions["x-portblock-count"]
+	if !ok {
+		return countRange{}, false
+	}
+	switch v := raw.(type) {
+	case float64:
+		return countRange{min: int(v), max: int(v)}, true
+	case string:
+		c, err := parseCountRange(v)
+		return c, err == nil
+	case []interface{}:
+		if len(v) == 2 {
+			lo, ok1 := v[0].(float64)
+			hi, ok2 := v[1].(float64)
+			if ok1 && ok2 && lo <= hi {
+				return countRange{min: int(lo), max: int(hi)}, true
+			}
+		}
+	}
+	return countRange{}, false
+}
+
+// arrayCountRange resolves how many items an array gets:
+// x-portblock-count, then --list-size, then 2-5, always clamped to minItems/maxItems
+func arrayCountRange(schema *openapi3.Schema) countRange {
+	c, ok := schemaCountRange(schema)
+	if !ok {
+		c = defaultListSize
+	}
+	if !c.isSet() {
+		c = countRange{min: 2, max: 5}
+	}
+	return clampCount(schema, c)
+}
+
+func clampCount(schema *openapi3.Schema, c countRange) countRange {
+	lo, hi := int(schema.MinItems), maxListSize
+	if schema.MaxItems != nil && int(*schema.MaxItems) < hi {
+		hi = int(*schema.MaxItems)
+	}
+	clamp := func(n int) int {
+		if n < lo {
+			n = lo
+		}
+		if n > hi {
+			n = hi
+		}
+		return n
+	}
+	return countRange{min: clamp(c.min), max: clamp(c.max)}
+}
+
+// parsePreferCount reads the Prefer: count=N hint
+func parsePreferCount(r *http.Request) (int, bool) {
+	v := parsePreference(r, "count")
+	if v == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// generateListPage generates a top-level array lazily: each item is seeded by
+// its index, so only the requested limit/offset window has to be built and
+// pages stay consistent with each other. returns the page and the total count
+func generateListPage(schema *openapi3.Schema, baseSeed int64, path string, count int, query url.Values) ([]interface{}, int) {
+	hasFilters := false
+	for key := range query {
+		if key != "limit" && key != "offset" {
+			hasFilters = true
+			break
+		}
+	}
+
+	item := func(i int) interface{} {
+		rng := seededRng(baseSeed, path+"#"+strconv.Itoa(i))
+		return generateFromSchema(schema.Items, rng, 1)
+	}
+
+	// filters need every item; pagination alone only needs the window
+	if hasFilters {
+		items := make([]interface{}, count)
+		for i := range items {
+			items[i] = item(i)
+		}
+		filtered := applyQueryParams(items, withoutPagination(query))
+		return applyQueryParams(filtered, query), len(filtered)
+	}
+
+	start, end := 0, count
+	if offset, err := strconv.Atoi(query.Get("offset")); err == nil && offset > 0 {
+		start = offset
+	}
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit >= 0 && start+limit < end {
+		end = start + limit
+	}
+	if start > end {
+		start = end
+	}
+	items := make([]interface{}, 0, end-start)
+	for i := start; i < end; i++ {
+		items = append(items, item(i))
+	}
+	return items, count
+}
+
+func withoutPagination(query url.Values) url.Values {
+	q := url.Values{}
+	for k, v := range query {
+		if k != "limit" && k != "offset" {
+			q[k] = v
+		}
+	}
+	return q
+}
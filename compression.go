@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gorilla/websocket"
+)
+
+var noCompress bool
+
+// encodings portblock speaks, most preferred first when the client weighs
+// them equally
+var encodings = []string{"br", "gzip", "deflate"}
+
+// compressHandler encodes responses with whatever Accept-Encoding allows
+func compressHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if noCompress || websocket.IsWebSocketUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		w.Header().Add("Vary", "Accept-Encoding")
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, head: r.Method == http.MethodHead}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks the best encoding the header accepts, "" for none
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+	weights := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		weights[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+	best, bestQ := "", 0.0
+	for _, enc := range encodings {
+		q, ok := weights[enc]
+		if !ok {
+			q, ok = weights["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// compressWriter decides when the status is written whether the response
+// gets encoded: not for empty statuses, ranges, already compressed media or
+// handlers that set their own Content-Encoding
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	head        bool
+	wroteHeader bool
+	encoder     io.WriteCloser
+	noBody      bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	h := cw.Header()
+	if code >= 200 && code != http.StatusNoContent && code != http.StatusNotModified && code != http.StatusPartialContent &&
+		h.Get("Content-Encoding") == "" && h.Get("Content-Range") == "" && compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		cw.encoder = newEncoder(cw.encoding, cw.ResponseWriter)
+	}
+	cw.noBody = cw.head || code == http.StatusNoContent || code == http.StatusNotModified
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.encoder == nil {
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.encoder.Write(b)
+}
+
+func (cw *compressWriter) Flush() {
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the encoded stream. an empty body still gets a valid one,
+// since clients that see Content-Encoding will try to decode it
+func (cw *compressWriter) Close() error {
+	if !cw.wroteHeader {
+		return nil
+	}
+	if cw.encoder == nil || cw.noBody {
+		return nil
+	}
+	return cw.encoder.Close()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func newEncoder(encoding string, w io.Writer) io.WriteCloser {
+	switch encoding {
+	case "br":
+		return brotli.NewWriter(w)
+	case "deflate":
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	}
+	return gzip.NewWriter(w)
+}
+
+// compressible skips media that's already compressed
+func compressible(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "":
+		return true
+	case strings.HasPrefix(mediaType, "image/") && mediaType != "image/svg+xml",
+		strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"):
+		return false
+	}
+	switch mediaType {
+	case "application/zip", "application/gzip", "application/x-gzip", "application/x-brotli",
+		"application/x-7z-compressed", "application/x-rar-compressed", "application/pdf":
+		return false
+	}
+	return true
+}
+
+// readRequestBody reads a request body of up to maxBodySize
+func readRequestBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, errBodyTooLarge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	return data, nil
+}
+
+// maxBodySize caps request bodies, before and after decompression
+const maxBodySize = 32 << 20
+
+var (
+	errUnsupportedEncoding = errors.New("unsupported content encoding")
+	errBodyTooLarge        = fmt.Errorf("request body is larger than %d MiB", maxBodySize>>20)
+)
+
+// decodeContentEncoding undoes the request's Content-Encoding, so validation
+// and handlers see the plain body. a body that inflates past maxBodySize is
+// errBodyTooLarge
+func decodeContentEncoding(r *http.Request, body []byte) ([]byte, error) {
+	header := r.Header.Get("Content-Encoding")
+	if header == "" || len(body) == 0 {
+		return body, nil
+	}
+	// codings are listed in the order they were applied
+	codings := strings.Split(header, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		var reader io.Reader
+		var err error
+		switch strings.ToLower(strings.TrimSpace(codings[i])) {
+		case "identity", "":
+			continue
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(bytes.NewReader(body))
+		case "deflate":
+			reader = flate.NewReader(bytes.NewReader(body))
+		case "br":
+			reader = brotli.NewReader(bytes.NewReader(body))
+		default:
+			return nil, fmt.Errorf("%w %q", errUnsupportedEncoding, codings[i])
+		}
+		if err == nil {
+			body, err = io.ReadAll(io.LimitReader(reader, maxBodySize+1))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s body: %v", strings.TrimSpace(codings[i]), err)
+		}
+		if len(body) > maxBodySize {
+			return nil, errBodyTooLarge
+		}
+	}
+	r.Header.Del("Content-Encoding")
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return body, nil
+}
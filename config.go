@@ -20,9 +20,33 @@ type Config struct {
 	NoAuth bool   `yaml:"no-auth" json:"no-auth"`
 	Watch  *bool  `yaml:"watch" json:"watch"`
 	Strict bool   `yaml:"strict" json:"strict"`
+	Now    string `yaml:"now,omitempty" json:"now,omitempty"`
+
+	NoCompress bool   `yaml:"no-compress,omitempty" json:"no-compress,omitempty"`
+	BasePath   string `yaml:"base-path,omitempty" json:"base-path,omitempty"`
+
+	ListSize    string `yaml:"list-size,omitempty" json:"list-size,omitempty"`
+	ErrorFormat string `yaml:"error-format,omitempty" json:"error-format,omitempty"`
+	SSEInterval string `yaml:"sse-interval,omitempty" json:"sse-interval,omitempty"`
+	WSInterval  string `yaml:"ws-interval,omitempty" json:"ws-interval,omitempty"`
+
+	// AsyncAPI is an AsyncAPI document served as WebSockets next to the spec
+	AsyncAPI string `yaml:"asyncapi,omitempty" json:"asyncapi,omitempty"`
 
 	WebhookTarget string `yaml:"webhook-target" json:"webhook-target"`
 	WebhookDelay  string `yaml:"webhook-delay" json:"webhook-delay"`
+
+	CORS *CORSConfig `yaml:"cors,omitempty" json:"cors,omitempty"`
+	TLS  *TLSConfig  `yaml:"tls,omitempty" json:"tls,omitempty"`
+
+	// Specs are served from one process when serve gets no spec argument,
+	// relative to the config file
+	Specs []SpecConfig `yaml:"specs,omitempty" json:"specs,omitempty"`
+
+	// Rules points at a field inference rules file, relative to the config file
+	Rules string `yaml:"rules,omitempty" json:"rules,omitempty"`
+
+	path string
 }
 
 func loadConfig() *Config {
@@ -65,6 +89,7 @@ func loadConfig() *Config {
 				continue
 			}
 		}
+		cfg.path = path
 		return cfg
 	}
 
@@ -92,9 +117,61 @@ func applyConfig(cfg *Config) {
 	if cfg.NoAuth && !noAuth {
 		noAuth = true
 	}
+	if cfg.NoCompress && !noCompress {
+		noCompress = true
+	}
+	if cfg.BasePath != "" && basePathFlag == "" {
+		basePathFlag = cfg.BasePath
+	}
+	if cfg.ListSize != "" && listSizeFlag == "" {
+		listSizeFlag = cfg.ListSize
+	}
+	if cfg.SSEInterval != "" && sseInterval == time.Second {
+		if d, err := time.ParseDuration(cfg.SSEInterval); err == nil && d > 0 {
+			sseInterval = d
+		}
+	}
+	if cfg.WSInterval != "" && wsInterval == time.Second {
+		if d, err := time.ParseDuration(cfg.WSInterval); err == nil && d > 0 {
+			wsInterval = d
+		}
+	}
+	if cfg.AsyncAPI != "" && asyncAPIFile == "" {
+		asyncAPIFile = cfg.AsyncAPI
+	}
+	if cfg.ErrorFormat != "" && errorFormat == "" {
+		errorFormat = cfg.ErrorFormat
+	}
+	if cfg.Now != "" && nowFlag == "" {
+		nowFlag = cfg.Now
+	}
 	if cfg.Strict && !strictMode {
 		strictMode = true
 	}
+	if cfg.CORS != nil {
+		if len(cors.Origins) == 0 {
+			cors.Origins = cfg.CORS.Origins
+		}
+		if !cors.Credentials {
+			cors.Credentials = cfg.CORS.Credentials
+		}
+		if cors.Fail == "" {
+			cors.Fail = cfg.CORS.Fail
+		}
+		cors.Methods = cfg.CORS.Methods
+		cors.Headers = cfg.CORS.Headers
+		cors.MaxAge = cfg.CORS.MaxAge
+	}
+	if cfg.TLS != nil {
+		tlsOpts.Enabled = tlsOpts.Enabled || cfg.TLS.Enabled
+		tlsOpts.MTLS = tlsOpts.MTLS || cfg.TLS.MTLS
+		if tlsOpts.Cert == "" && tlsOpts.Key == "" {
+			tlsOpts.Cert, tlsOpts.Key = cfg.TLS.Cert, cfg.TLS.Key
+		}
+		if tlsOpts.ClientCA == "" {
+			tlsOpts.ClientCA = cfg.TLS.ClientCA
+		}
+	}
 	if cfg.WebhookTarget != "" && webhookTarget == "" {
 		webhookTarget = cfg.WebhookTarget
 	}
@@ -105,6 +182,19 @@ func applyConfig(cfg *Config) {
 	}
 }
 
+// applyDataFlags parses the flags every mock command shares for generated
+// data, once the config file has filled them in
+func applyDataFlags() error {
+	if listSizeFlag != "" {
+		c, err := parseCountRange(listSizeFlag)
+		if err != nil {
+			return fmt.Errorf("--list-size: %w", err)
+		}
+		defaultListSize = c
+	}
+	return applyNow(nowFlag)
+}
+
 func applyConfigWatch(cfg *Config, cmd *cobra.Command) {
 	if cfg == nil {
 		return
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// CORSConfig is the cors section of .portblock.yaml
+type CORSConfig struct {
+	Origins     []string `yaml:"origins,omitempty" json:"origins,omitempty"`
+	Methods     []string `yaml:"methods,omitempty" json:"methods,omitempty"`
+	Headers     []string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Credentials bool     `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+	MaxAge      int      `yaml:"max-age,omitempty" json:"max-age,omitempty"`
+	Fail        string   `yaml:"fail,omitempty" json:"fail,omitempty"`
+}
+
+// cors is the active policy. the zero value allows any origin, method and
+// header, like a mock should
+var cors CORSConfig
+
+// corsFailModes simulate the ways CORS breaks in browsers
+var corsFailModes = map[string]string{
+	"preflight":   "preflight requests are rejected with 403",
+	"missing":     "no CORS headers at all",
+	"origin":      "Access-Control-Allow-Origin names a different origin",
+	"credentials": "credentialed requests get a wildcard origin",
+}
+
+func validateCORS(c CORSConfig) error {
+	if c.Fail == "" {
+		return nil
+	}
+	if _, ok := corsFailModes[c.Fail]; !ok {
+		modes := make([]string, 0, len(corsFailModes))
+		for m := range corsFailModes {
+			modes = append(modes, m)
+		}
+		sort.Strings(modes)
+		return fmt.Errorf("invalid --cors-fail %q: use one of %s", c.Fail, strings.Join(modes, ", "))
+	}
+	return nil
+}
+
+// originAllowed matches an Origin against the configured origins, which may be
+// "*" or contain a wildcard subdomain like https://*.example.com
+func (c CORSConfig) originAllowed(origin string) bool {
+	if len(c.Origins) == 0 {
+		return true
+	}
+	for _, allowed := range c.Origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(allowed, "*"); ok {
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c CORSConfig) wildcard() bool {
+	if c.Credentials {
+		return false
+	}
+	if len(c.Origins) == 0 {
+		return true
+	}
+	for _, o := range c.Origins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// isPreflight reports whether r is a CORS preflight rather than a real OPTIONS call
+func isPreflight(r *http.Request) bool {
+	return r.Method == "OPTIONS" && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// applyCORS sets the CORS headers for a request. it returns false when the
+// request has been answered (preflights, simulated failures)
+func (s *MockServer) applyCORS(w http.ResponseWriter, r *http.Request) bool {
+	pathItem := s.findPathItem(r.URL.Path)
+
+	// spec-defined OPTIONS operations answer plain OPTIONS calls themselves.
+	// real preflights are always answered here: browsers never send them with
+	// credentials, so they must not reach the operation's auth
+	specOptions := pathItem != nil && pathItem.Options != nil
+	preflight := isPreflight(r) || (r.Method == "OPTIONS" && !specOptions)
+
+	var methods []string
+	if preflight {
+		methods = s.allowedMethods(r.URL.Path)
+	}
+	return corsHeaders(w, r, preflight, methods, exposedHeaders(pathItem))
+}
+
+// withCORS applies the same policy to a handler without a spec behind it,
+// answering every OPTIONS request as a preflight
+func withCORS(next http.HandlerFunc, methods ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed := methods
+		if len(cors.Methods) > 0 {
+			allowed = cors.Methods
+		}
+		if corsHeaders(w, r, r.Method == "OPTIONS", allowed, nil) {
+			next(w, r)
+		}
+	}
+}
+
+// corsHeaders sets the headers the active policy calls for. methods are
+// what a preflight allows, exposed the response headers browser code may read
+func corsHeaders(w http.ResponseWriter, r *http.Request, preflight bool, methods, exposed []string) bool {
+	h := w.Header()
+	origin := r.Header.Get("Origin")
+
+	switch cors.Fail {
+	case "missing":
+		return !preflight || answerPreflight(w, r, nil)
+	case "preflight":
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+			return false
+		}
+	}
+
+	if origin != "" {
+		h.Add("Vary", "Origin")
+	}
+	switch {
+	case cors.Fail == "origin" && origin != "":
+		h.Set("Access-Control-Allow-Origin", "https://not-"+strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://"))
+	case cors.Fail == "credentials" && origin != "":
+		h.Set("Access-Control-Allow-Origin", "*")
+	case cors.wildcard() && origin == "":
+		h.Set("Access-Control-Allow-Origin", "*")
+	case origin != "" && cors.originAllowed(origin):
+		if cors.wildcard() {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cors.Credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+
+	if len(exposed) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(exposed, ", "))
+	}
+
+	if preflight {
+		return answerPreflight(w, r, methods)
+	}
+	return true
+}
+
+// answerPreflight writes the preflight headers and a bare 204. nil methods
+// leaves the headers off, for the "missing" failure mode
+func answerPreflight(w http.ResponseWriter, r *http.Request, methods []string) bool {
+	if methods != nil {
+		h := w.Header()
+		h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		if len(cors.Headers) > 0 {
+			h.Set("Access-Control-Allow-Headers", strings.Join(cors.Headers, ", "))
+		} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			h.Set("Access-Control-Allow-Headers", requested)
+		} else {
+			h.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Prefer, Accept")
+		}
+		if cors.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(cors.MaxAge))
+		}
+	}
+	w.WriteHeader(204)
+	return false
+}
+
+// allowedMethods is the configured list, else whatever the spec declares for the path
+func (s *MockServer) allowedMethods(reqPath string) []string {
+	if len(cors.Methods) > 0 {
+		return cors.Methods
+	}
+	pathItem := s.findPathItem(reqPath)
+	if pathItem == nil {
+		return []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	}
+	var methods []string
+	for _, m := range []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"} {
+		if getOperation(pathItem, m) != nil {
+			methods = append(methods, m)
+		}
+	}
+	return append(methods, "OPTIONS")
+}
+
+// findPathItem matches a request path regardless of method
+func (s *MockServer) findPathItem(reqPath string) *openapi3.PathItem {
+	if node, _ := s.routes.lookup(reqPath, ""); node != nil {
+		return node.item
+	}
+	return nil
+}
+
+// exposedHeaders lists the response headers the path's operations declare,
+// plus the ones portblock sets itself, so browser code can read them
+func exposedHeaders(pathItem *openapi3.PathItem) []string {
+	seen := map[string]bool{}
+	exposed := []string{"Location", "X-Total-Count"}
+	for _, h := range exposed {
+		seen[strings.ToLower(h)] = true
+	}
+	if pathItem == nil {
+		return exposed
+	}
+	var declared []string
+	for _, op := range pathItem.Operations() {
+		if op.Responses == nil {
+			continue
+		}
+		for _, resp := range op.Responses.Map() {
+			if resp.Value == nil {
+				continue
+			}
+			for name := range resp.Value.Headers {
+				if !seen[strings.ToLower(name)] && !strings.EqualFold(name, "Content-Type") {
+					seen[strings.ToLower(name)] = true
+					declared = append(declared, name)
+				}
+			}
+		}
+	}
+	sort.Strings(declared)
+	return append(exposed, declared...)
+}
@@ -30,11 +30,25 @@ export default defineConfig({
         text: 'Features',
         items: [
           { text: 'Smart Fake Data', link: '/features/smart-fake-data' },
+          { text: 'Swagger 2.0', link: '/features/swagger' },
+          { text: 'OpenAPI 3.1', link: '/features/openapi-31' },
           { text: 'Stateful CRUD', link: '/features/stateful-crud' },
           { text: 'Request Validation', link: '/features/request-validation' },
+          { text: 'Base Paths & Routing', link: '/features/base-paths' },
           { text: 'Prefer Header', link: '/features/prefer-header' },
           { text: 'Query Parameters', link: '/features/query-params' },
+          { text: 'Content Types', link: '/features/content-types' },
+          { text: 'Binary Responses', link: '/features/binary-responses' },
+          { text: 'Server-Sent Events', link: '/features/server-sent-events' },
+          { text: 'WebSockets', link: '/features/websockets' },
+          { text: 'GraphQL', link: '/features/graphql' },
+          { text: 'gRPC', link: '/features/grpc' },
+          { text: 'SOAP', link: '/features/soap' },
           { text: 'Auth Simulation', link: '/features/auth-simulation' },
+          { text: 'CORS', link: '/features/cors' },
+          { text: 'HTTPS & HTTP/2', link: '/features/tls' },
+          { text: 'Compression', link: '/features/compression' },
+          { text: 'Multiple Specs', link: '/features/multiple-specs' },
           { text: 'Proxy Mode', link: '/features/proxy-mode' },
           { text: 'Replay Mode', link: '/features/replay' },
           { text: 'Chaos Mode', link: '/features/chaos-mode' },
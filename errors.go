@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// errorFormat is how mock-generated errors look when the spec doesn't declare
+// an error response: "problem" (RFC 7807, the default) or "simple" ({"error": ...})
+var errorFormat string
+
+func parseErrorFormat(s string) (string, error) {
+	switch s {
+	case "", "problem":
+		return "problem", nil
+	case "simple":
+		return "simple", nil
+	}
+	return "", fmt.Errorf("invalid --error-format %q: use problem or simple", s)
+}
+
+// mockError is an error portblock generates itself (validation, auth, chaos...)
+type mockError struct {
+	status  int
+	detail  string
+	details []map[string]string
+}
+
+// writeError writes a mock-generated error. if the operation declares a
+// response for the status (exact, 4XX/5XX or default) its schema shapes the
+// body; otherwise it's problem+json or the simple format
+func (s *MockServer) writeError(w http.ResponseWriter, r *http.Request, op *openapi3.Operation, e mockError) {
+	if resp := errorResponse(op, e.status); resp != nil {
+		if mediaType, media := errorMedia(r, resp); media != nil && media.Schema != nil {
+			rng := seededRng(s.seed, r.URL.Path+"#error"+strconv.Itoa(e.status))
+			body := generateFromSchema(media.Schema, rng, 0)
+			if m, ok := body.(map[string]interface{}); ok {
+				fillErrorFields(m, media.Schema.Value, r, e)
+			}
+			writeSchemaResponse(w, mediaType, e.status, body, media.Schema, s.propOrder)
+			return
+		}
+	}
+
+	if errorFormat == "simple" {
+		body := map[string]interface{}{"error": e.detail}
+		if len(e.details) > 0 {
+			body["details"] = e.details
+		}
+		writeResponse(w, "application/json", e.status, body)
+		return
+	}
+	writeResponse(w, "application/problem+json", e.status, problemDetails(r, e))
+}
+
+// problemDetails builds an RFC 7807 body
+func problemDetails(r *http.Request, e mockError) map[string]interface{} {
+	body := map[string]interface{}{
+		"type":     "about:blank",
+		"title":    http.StatusText(e.status),
+		"status":   e.status,
+		"detail":   e.detail,
+		"instance": mountedPath(r),
+	}
+	if len(e.details) > 0 {
+		body["errors"] = e.details
+	}
+	return body
+}
+
+// errorResponse finds the declared response for an error status. default
+// only counts when the operation declares no 2xx, since many specs use it
+// for their success response
+func errorResponse(op *openapi3.Operation, status int) *openapi3.Response {
+	if op == nil || op.Responses == nil {
+		return nil
+	}
+	if ref := op.Responses.Status(status); ref != nil && ref.Value != nil {
+		return ref.Value
+	}
+	if hasSuccessResponse(op) {
+		return nil
+	}
+	if ref := op.Responses.Default(); ref != nil && ref.Value != nil {
+		return ref.Value
+	}
+	return nil
+}
+
+func hasSuccessResponse(op *openapi3.Operation) bool {
+	for code := range op.Responses.Map() {
+		if strings.HasPrefix(code, "2") {
+			return true
+		}
+	}
+	return false
+}
+
+// errorMedia picks the error response's media type the client accepts,
+// else the first JSON one
+func errorMedia(r *http.Request, resp *openapi3.Response) (string, *openapi3.MediaType) {
+	types := make([]string, 0, len(resp.Content))
+	for mt := range resp.Content {
+		if !isBinaryResponse(resp, mt) {
+			types = append(types, mt)
+		}
+	}
+	if len(types) == 0 {
+		return "", nil
+	}
+	sort.Slice(types, func(i, j int) bool {
+		ri, rj := mediaRank(types[i], resp), mediaRank(types[j], resp)
+		if ri != rj {
+			return ri < rj
+		}
+		return types[i] < types[j]
+	})
+	mt := negotiateMediaType(r, types)
+	if mt == "" {
+		mt = types[0]
+	}
+	return mt, resp.Content[mt]
+}
+
+// fillErrorFields overwrites the generated error's well-known fields with what
+// actually went wrong. only properties the schema declares are touched
+func fillErrorFields(m map[string]interface{}, schema *openapi3.Schema, r *http.Request, e mockError) {
+	if schema == nil {
+		return
+	}
+	for name, prop := range schema.Properties {
+		if prop.Value == nil {
+			continue
+		}
+		switch normalizeFieldName(name) {
+		case "status", "code", "statuscode":
+			if prop.Value.Type.Is("string") {
+				m[name] = strconv.Itoa(e.status)
+			} else if prop.Value.Type.Is("integer") || prop.Value.Type.Is("number") {
+				m[name] = e.status
+			}
+		case "title", "error":
+			if prop.Value.Type.Is("string") && len(prop.Value.Enum) == 0 {
+				m[name] = http.StatusText(e.status)
+			}
+		case "detail", "message", "description", "errordescription":
+			if prop.Value.Type.Is("string") {
+				m[name] = e.detail
+			}
+		case "type":
+			if prop.Value.Type.Is("string") && len(prop.Value.Enum) == 0 && prop.Value.Format == "uri" {
+				m[name] = "about:blank"
+			}
+		case "instance", "path":
+			if prop.Value.Type.Is("string") {
+				m[name] = mountedPath(r)
+			}
+		case "errors", "details", "violations", "invalidparams", "fielderrors", "validationerrors", "fields":
+			if prop.Value.Type.Is("array") {
+				m[name] = errorItems(prop.Value.Items, e.details)
+			}
+		}
+	}
+}
+
+// normalizeFieldName lowercases and drops separators: field_errors -> fielderrors
+func normalizeFieldName(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+// errorItems maps validation details onto the schema's error item shape
+func errorItems(items *openapi3.SchemaRef, details []map[string]string) []interface{} {
+	result := make([]interface{}, 0, len(details))
+	for _, d := range details {
+		if items == nil || items.Value == nil || !items.Value.Type.Is("object") {
+			result = append(result, d["message"])
+			continue
+		}
+		item := map[string]interface{}{}
+		for name, prop := range items.Value.Properties {
+			if prop.Value == nil || !prop.Value.Type.Is("string") {
+				continue
+			}
+			switch normalizeFieldName(name) {
+			case "field", "name", "param", "parameter", "property", "path", "pointer", "location":
+				if d["field"] != "" {
+					item[name] = d["field"]
+				}
+			case "message", "detail", "reason", "description", "error":
+				item[name] = d["message"]
+			}
+		}
+		result = append(result, item)
+	}
+	return result
+}
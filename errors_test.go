@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func responsesOp(codes ...string) *openapi3.Operation {
+	op := &openapi3.Operation{Responses: openapi3.NewResponses()}
+	op.Responses.Delete("default")
+	for _, code := range codes {
+		desc := code
+		op.Responses.Set(code, &openapi3.ResponseRef{Value: &openapi3.Response{Description: &desc}})
+	}
+	return op
+}
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		op     *openapi3.Operation
+		status int
+		want   string // the chosen response's description, "" for none
+	}{
+		{"exact status", responsesOp("200", "404", "default"), 404, "404"},
+		{"range", responsesOp("200", "4XX"), 401, "4XX"},
+		{"only default", responsesOp("default"), 404, "default"},
+		{"default next to a 2xx", responsesOp("200", "default"), 404, ""},
+		{"default next to a 2XX range", responsesOp("2XX", "default"), 500, ""},
+		{"nothing declared for errors", responsesOp("201"), 400, ""},
+	}
+	for _, tt := range tests {
+		resp := errorResponse(tt.op, tt.status)
+		got := ""
+		if resp != nil {
+			got = *resp.Description
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+// storeEvent describes a change to the in-memory store
+type storeEvent struct {
+	Kind     string // created, updated or deleted
+	Resource string
+	ID       string
+	Data     interface{}
+}
+
+// Name is the event name used by webhooks, SSE and WebSocket publishers
+func (e storeEvent) Name() string {
+	return e.Resource + "." + e.Kind
+}
+
+// Subscribe returns a channel of store changes and a func to stop listening.
+// slow subscribers miss events rather than blocking writes
+func (s *Store) Subscribe() (<-chan storeEvent, func()) {
+	ch := make(chan storeEvent, 64)
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[chan storeEvent]struct{})
+	}
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subs, ch)
+		s.mu.Unlock()
+	}
+}
+
+// publish must be called with s.mu held
+func (s *Store) publish(e storeEvent) {
+	if len(s.subs) == 0 {
+		return
+	}
+	// handlers update stored objects in place, so subscribers get a copy
+	if m, ok := e.Data.(map[string]interface{}); ok {
+		snapshot := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			snapshot[k] = v
+		}
+		e.Data = snapshot
+	}
+	for ch := range s.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,979 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/spf13/cobra"
+)
+
+var graphqlPath = "/graphql"
+
+func runGraphQL(cmd *cobra.Command, args []string) error {
+	schemaFile := args[0]
+
+	cfg := loadConfig()
+	applyConfig(cfg)
+	if err := applyConfigRules(cfg); err != nil {
+		return err
+	}
+	if err := applyDataFlags(); err != nil {
+		return err
+	}
+	if err := validateCORS(cors); err != nil {
+		return err
+	}
+
+	sdl, err := os.ReadFile(schemaFile)
+	if err != nil {
+		return err
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	mock, err := newGraphQLMock(string(sdl), seed)
+	if err != nil {
+		return fmt.Errorf("failed to load schema: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(graphqlPath, withCORS(mock.handle, "GET", "POST", "OPTIONS"))
+
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}
+	if err := configureTLS(srv); err != nil {
+		return err
+	}
+
+	fmt.Println(renderBanner("graphql", schemaFile, port, seed, delay, false, false))
+	fmt.Print(renderRoutes(mock.routes()))
+	fmt.Print(renderReady(port))
+
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		<-sigCh
+		fmt.Print(renderShutdown())
+		srv.Shutdown(context.Background())
+	}()
+
+	return serveHTTP(srv)
+}
+
+// graphQLMock serves a schema built from SDL. every field resolves to data
+// from the fake-data engine; types with an id field are kept in a Store so
+// mutations stick
+type graphQLMock struct {
+	schema graphql.Schema
+	store  *Store
+	seed   int64
+
+	defs        map[string]ast.Node
+	types       map[string]graphql.Type
+	implementer map[string][]string // interface/union -> object type names
+	scalars     map[string]*openapi3.Schema
+	queryName   string
+	mutateName  string
+}
+
+func newGraphQLMock(sdl string, seed int64) (*graphQLMock, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: sdl})
+	if err != nil {
+		return nil, err
+	}
+	m := &graphQLMock{
+		store:       NewStore(),
+		seed:        seed,
+		defs:        map[string]ast.Node{},
+		types:       map[string]graphql.Type{},
+		implementer: map[string][]string{},
+		scalars:     map[string]*openapi3.Schema{},
+		queryName:   "Query",
+		mutateName:  "Mutation",
+	}
+
+	var extensions []*ast.ObjectDefinition
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.ObjectDefinition:
+			m.defs[d.Name.Value] = d
+			for _, iface := range d.Interfaces {
+				m.implementer[iface.Name.Value] = append(m.implementer[iface.Name.Value], d.Name.Value)
+			}
+		case *ast.InterfaceDefinition:
+			m.defs[d.Name.Value] = d
+		case *ast.UnionDefinition:
+			m.defs[d.Name.Value] = d
+			for _, t := range d.Types {
+				m.implementer[d.Name.Value] = append(m.implementer[d.Name.Value], t.Name.Value)
+			}
+		case *ast.EnumDefinition:
+			m.defs[d.Name.Value] = d
+		case *ast.InputObjectDefinition:
+			m.defs[d.Name.Value] = d
+		case *ast.ScalarDefinition:
+			m.defs[d.Name.Value] = d
+		case *ast.TypeExtensionDefinition:
+			extensions = append(extensions, d.Definition)
+		case *ast.SchemaDefinition:
+			for _, op := range d.OperationTypes {
+				switch op.Operation {
+				case "query":
+					m.queryName = op.Type.Name.Value
+				case "mutation":
+					m.mutateName = op.Type.Name.Value
+				}
+			}
+		}
+	}
+	// extend Query { ... } adds fields to the original type
+	for _, ext := range extensions {
+		if base, ok := m.defs[ext.Name.Value].(*ast.ObjectDefinition); ok {
+			base.Fields = append(base.Fields, ext.Fields...)
+		} else {
+			m.defs[ext.Name.Value] = ext
+		}
+	}
+
+	query, ok := m.namedType(m.queryName).(*graphql.Object)
+	if !ok {
+		return nil, fmt.Errorf("schema has no %s type", m.queryName)
+	}
+	config := graphql.SchemaConfig{Query: query}
+	if _, ok := m.defs[m.mutateName]; ok {
+		config.Mutation, _ = m.namedType(m.mutateName).(*graphql.Object)
+	}
+	// types only reachable through interfaces still need to be in the schema
+	names := make([]string, 0, len(m.defs))
+	for name := range m.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch m.defs[name].(type) {
+		case *ast.ObjectDefinition:
+			config.Types = append(config.Types, m.namedType(name))
+			m.scalars[name] = m.buildScalarSchema(name)
+		case *ast.InterfaceDefinition:
+			m.scalars[name] = m.buildScalarSchema(name)
+		}
+	}
+
+	m.schema, err = graphql.NewSchema(config)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// --------------- Schema Building ---------------
+
+func (m *graphQLMock) namedType(name string) graphql.Type {
+	switch name {
+	case "Int":
+		return graphql.Int
+	case "Float":
+		return graphql.Float
+	case "String":
+		return graphql.String
+	case "Boolean":
+		return graphql.Boolean
+	case "ID":
+		return graphql.ID
+	}
+	if t, ok := m.types[name]; ok {
+		return t
+	}
+
+	var t graphql.Type
+	switch d := m.defs[name].(type) {
+	case *ast.ObjectDefinition:
+		obj := graphql.NewObject(graphql.ObjectConfig{
+			Name:        name,
+			Description: description(d.Description),
+			Interfaces: (graphql.InterfacesThunk)(func() []*graphql.Interface {
+				var ifaces []*graphql.Interface
+				for _, i := range d.Interfaces {
+					if iface, ok := m.namedType(i.Name.Value).(*graphql.Interface); ok {
+						ifaces = append(ifaces, iface)
+					}
+				}
+				return ifaces
+			}),
+			Fields: (graphql.FieldsThunk)(func() graphql.Fields { return m.fields(name, d.Fields) }),
+		})
+		t = obj
+	case *ast.InterfaceDefinition:
+		t = graphql.NewInterface(graphql.InterfaceConfig{
+			Name:        name,
+			Description: description(d.Description),
+			Fields:      (graphql.FieldsThunk)(func() graphql.Fields { return m.fields(name, d.Fields) }),
+			ResolveType: m.resolveType,
+		})
+	case *ast.UnionDefinition:
+		t = graphql.NewUnion(graphql.UnionConfig{
+			Name:        name,
+			Description: description(d.Description),
+			Types: (graphql.UnionTypesThunk)(func() []*graphql.Object {
+				var objs []*graphql.Object
+				for _, member := range d.Types {
+					if obj, ok := m.namedType(member.Name.Value).(*graphql.Object); ok {
+						objs = append(objs, obj)
+					}
+				}
+				return objs
+			}),
+			ResolveType: m.resolveType,
+		})
+	case *ast.EnumDefinition:
+		values := graphql.EnumValueConfigMap{}
+		for _, v := range d.Values {
+			values[v.Name.Value] = &graphql.EnumValueConfig{Value: v.Name.Value, Description: description(v.Description)}
+		}
+		t = graphql.NewEnum(graphql.EnumConfig{Name: name, Values: values, Description: description(d.Description)})
+	case *ast.InputObjectDefinition:
+		t = graphql.NewInputObject(graphql.InputObjectConfig{
+			Name:        name,
+			Description: description(d.Description),
+			Fields: (graphql.InputObjectConfigFieldMapThunk)(func() graphql.InputObjectConfigFieldMap {
+				fields := graphql.InputObjectConfigFieldMap{}
+				for _, f := range d.Fields {
+					fields[f.Name.Value] = &graphql.InputObjectFieldConfig{
+						Type:         m.inputType(f.Type),
+						DefaultValue: literalValue(f.DefaultValue),
+						Description:  description(f.Description),
+					}
+				}
+				return fields
+			}),
+		})
+	default:
+		// custom scalars (DateTime, JSON, ...) pass values through as-is
+		t = graphql.NewScalar(graphql.ScalarConfig{
+			Name:         name,
+			Serialize:    func(v interface{}) interface{} { return v },
+			ParseValue:   func(v interface{}) interface{} { return v },
+			ParseLiteral: literalValue,
+		})
+	}
+	m.types[name] = t
+	return t
+}
+
+func (m *graphQLMock) fields(typeName string, defs []*ast.FieldDefinition) graphql.Fields {
+	fields := graphql.Fields{}
+	for _, f := range defs {
+		args := graphql.FieldConfigArgument{}
+		for _, a := range f.Arguments {
+			args[a.Name.Value] = &graphql.ArgumentConfig{
+				Type:         m.inputType(a.Type),
+				DefaultValue: literalValue(a.DefaultValue),
+				Description:  description(a.Description),
+			}
+		}
+		field := &graphql.Field{
+			Name:              f.Name.Value,
+			Type:              m.outputType(f.Type),
+			Args:              args,
+			Description:       description(f.Description),
+			DeprecationReason: deprecationReason(f.Directives),
+		}
+		switch typeName {
+		case m.queryName:
+			field.Resolve = m.resolveQuery(f)
+		case m.mutateName:
+			field.Resolve = m.resolveMutation(f)
+		default:
+			field.Resolve = m.resolveField(typeName, f)
+		}
+		fields[f.Name.Value] = field
+	}
+	return fields
+}
+
+func (m *graphQLMock) outputType(t ast.Type) graphql.Output {
+	switch v := t.(type) {
+	case *ast.NonNull:
+		return graphql.NewNonNull(m.outputType(v.Type))
+	case *ast.List:
+		return graphql.NewList(m.outputType(v.Type))
+	case *ast.Named:
+		return m.namedType(v.Name.Value)
+	}
+	return graphql.String
+}
+
+func (m *graphQLMock) inputType(t ast.Type) graphql.Input {
+	switch v := t.(type) {
+	case *ast.NonNull:
+		return graphql.NewNonNull(m.inputType(v.Type))
+	case *ast.List:
+		return graphql.NewList(m.inputType(v.Type))
+	case *ast.Named:
+		if in, ok := m.namedType(v.Name.Value).(graphql.Input); ok {
+			return in
+		}
+	}
+	return graphql.String
+}
+
+// resolveType reads the __typename every generated object carries
+func (m *graphQLMock) resolveType(p graphql.ResolveTypeParams) *graphql.Object {
+	if obj, ok := p.Value.(map[string]interface{}); ok {
+		if name, ok := obj["__typename"].(string); ok {
+			t, _ := m.namedType(name).(*graphql.Object)
+			return t
+		}
+	}
+	return nil
+}
+
+func description(s *ast.StringValue) string {
+	if s == nil {
+		return ""
+	}
+	return s.Value
+}
+
+func deprecationReason(directives []*ast.Directive) string {
+	for _, d := range directives {
+		if d.Name.Value != "deprecated" {
+			continue
+		}
+		for _, a := range d.Arguments {
+			if a.Name.Value == "reason" {
+				if s, ok := a.Value.(*ast.StringValue); ok {
+					return s.Value
+				}
+			}
+		}
+		return "No longer supported"
+	}
+	return ""
+}
+
+// literalValue converts an SDL/query literal to a plain Go value
+func literalValue(v ast.Value) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case *ast.IntValue:
+		n, _ := strconv.Atoi(val.Value)
+		return n
+	case *ast.FloatValue:
+		f, _ := strconv.ParseFloat(val.Value, 64)
+		return f
+	case *ast.StringValue:
+		return val.Value
+	case *ast.BooleanValue:
+		return val.Value
+	case *ast.EnumValue:
+		return val.Value
+	case *ast.ListValue:
+		list := make([]interface{}, len(val.Values))
+		for i, item := range val.Values {
+			list[i] = literalValue(item)
+		}
+		return list
+	case *ast.ObjectValue:
+		obj := map[string]interface{}{}
+		for _, f := range val.Fields {
+			obj[f.Name.Value] = literalValue(f.Value)
+		}
+		return obj
+	}
+	return nil
+}
+
+// --------------- Type Helpers ---------------
+
+// unwrap strips NonNull and List wrappers, reporting whether there was a list
+func unwrap(t ast.Type) (string, bool) {
+	list := false
+	for {
+		switch v := t.(type) {
+		case *ast.NonNull:
+			t = v.Type
+		case *ast.List:
+			list = true
+			t = v.Type
+		case *ast.Named:
+			return v.Name.Value, list
+		default:
+			return "", list
+		}
+	}
+}
+
+func (m *graphQLMock) isComposite(name string) bool {
+	switch m.defs[name].(type) {
+	case *ast.ObjectDefinition, *ast.InterfaceDefinition, *ast.UnionDefinition:
+		return true
+	}
+	return false
+}
+
+// hasID reports whether an object type is an entity the store keeps
+func (m *graphQLMock) hasID(name string) bool {
+	obj, ok := m.defs[name].(*ast.ObjectDefinition)
+	if !ok {
+		return false
+	}
+	for _, f := range obj.Fields {
+		if f.Name.Value == "id" {
+			return true
+		}
+	}
+	return false
+}
+
+// entityFor finds the stored type behind a field: the field's own type, a
+// field of a payload type (CreateUserPayload.user), or a type named in the
+// field name (deleteUser -> User)
+func (m *graphQLMock) entityFor(f *ast.FieldDefinition) (string, string) {
+	name, _ := unwrap(f.Type)
+	if m.hasID(name) {
+		return name, ""
+	}
+	if obj, ok := m.defs[name].(*ast.ObjectDefinition); ok {
+		for _, inner := range obj.Fields {
+			if innerName, list := unwrap(inner.Type); !list && m.hasID(innerName) {
+				return innerName, inner.Name.Value
+			}
+		}
+	}
+	tokens := nameTokens(f.Name.Value)
+	for i := len(tokens) - 1; i >= 0; i-- {
+		for typeName := range m.defs {
+			lower := strings.ToLower(typeName)
+			if m.hasID(typeName) && (lower == tokens[i] || lower+"s" == tokens[i]) {
+				return typeName, ""
+			}
+		}
+	}
+	return "", ""
+}
+
+// scalarSchema maps the scalar fields of an object type to an OpenAPI schema,
+// so one generateObject call fills them with related fake data. they're all
+// built up front, since resolvers run concurrently
+func (m *graphQLMock) scalarSchema(typeName string) *openapi3.Schema {
+	if s, ok := m.scalars[typeName]; ok {
+		return s
+	}
+	return m.buildScalarSchema(typeName)
+}
+
+func (m *graphQLMock) buildScalarSchema(typeName string) *openapi3.Schema {
+	schema := &openapi3.Schema{Type: &openapi3.Types{"object"}, Properties: openapi3.Schemas{}}
+	var fields []*ast.FieldDefinition
+	switch d := m.defs[typeName].(type) {
+	case *ast.ObjectDefinition:
+		fields = d.Fields
+	case *ast.InterfaceDefinition:
+		fields = d.Fields
+	}
+	for _, f := range fields {
+		name, list := unwrap(f.Type)
+		if m.isComposite(name) {
+			continue
+		}
+		prop := m.leafSchema(name)
+		if list {
+			prop = &openapi3.Schema{Type: &openapi3.Types{"array"}, Items: openapi3.NewSchemaRef("", prop)}
+		}
+		schema.Properties[f.Name.Value] = openapi3.NewSchemaRef("", prop)
+	}
+	return schema
+}
+
+// leafSchema describes a scalar or enum for the fake-data engine
+func (m *graphQLMock) leafSchema(name string) *openapi3.Schema {
+	if enum, ok := m.defs[name].(*ast.EnumDefinition); ok {
+		s := openapi3.NewStringSchema()
+		for _, v := range enum.Values {
+			s.Enum = append(s.Enum, v.Name.Value)
+		}
+		return s
+	}
+	switch strings.ToLower(name) {
+	case "int", "long":
+		return openapi3.NewIntegerSchema()
+	case "float", "decimal":
+		return openapi3.NewFloat64Schema()
+	case "boolean":
+		return openapi3.NewBoolSchema()
+	case "id", "uuid":
+		return openapi3.NewUUIDSchema()
+	case "datetime", "timestamp", "instant":
+		return openapi3.NewDateTimeSchema()
+	case "date":
+		return openapi3.NewStringSchema().WithFormat("date")
+	case "url", "uri":
+		return openapi3.NewStringSchema().WithFormat("uri")
+	case "email", "emailaddress":
+		return openapi3.NewStringSchema().WithFormat("email")
+	case "json", "jsonobject", "object", "map":
+		return openapi3.NewObjectSchema()
+	}
+	return openapi3.NewStringSchema()
+}
+
+// --------------- Resolvers ---------------
+
+// generate builds one object of a type (picking an implementation for
+// interfaces and unions), seeded by key so the same path gives the same data
+func (m *graphQLMock) generate(typeName, key string) map[string]interface{} {
+	rng := seededRng(m.seed, "graphql:"+key)
+	if impls := m.implementer[typeName]; len(impls) > 0 {
+		typeName = impls[rng.Intn(len(impls))]
+	}
+	obj, _ := generateObject(m.scalarSchema(typeName), rng, 0, typeName).(map[string]interface{})
+	if obj == nil {
+		obj = map[string]interface{}{}
+	}
+	obj["__typename"] = typeName
+	obj["__key"] = key
+	return obj
+}
+
+// generateList builds a list of a type, honoring first/last/limit arguments
+func (m *graphQLMock) generateList(typeName, key string, args map[string]interface{}) []interface{} {
+	rng := seededRng(m.seed, "graphql:"+key+"#count")
+	count := arrayCountRange(&openapi3.Schema{}).pick(rng)
+	if n, ok := limitArg(args); ok {
+		count = n
+	}
+	items := make([]interface{}, count)
+	for i := range items {
+		items[i] = m.generate(typeName, key+"["+strconv.Itoa(i)+"]")
+	}
+	return items
+}
+
+// generateLeaf generates a scalar or enum value for a field name
+func (m *graphQLMock) generateLeaf(typeName, fieldName, key string, list bool) interface{} {
+	schema := m.leafSchema(typeName)
+	if list {
+		schema = &openapi3.Schema{Type: &openapi3.Types{"array"}, Items: openapi3.NewSchemaRef("", schema)}
+	}
+	rng := seededRng(m.seed, "graphql:"+key)
+	return generateFromSchemaWithName(openapi3.NewSchemaRef("", schema), rng, 0, fieldName, "", nil)
+}
+
+func limitArg(args map[string]interface{}) (int, bool) {
+	for _, name := range []string{"first", "last", "limit", "take", "count", "size", "pageSize"} {
+		if n, ok := args[name].(int); ok && n >= 0 {
+			if n > maxListSize {
+				n = maxListSize
+			}
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+func offsetArg(args map[string]interface{}) int {
+	for _, name := range []string{"offset", "skip"} {
+		if n, ok := args[name].(int); ok && n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// idArg finds the id a field is looked up by: an id argument, or an ID
+// argument named after the type (user(userId: ...))
+func idArg(f *ast.FieldDefinition, entity string, args map[string]interface{}) (string, bool) {
+	for _, a := range f.Arguments {
+		name := a.Name.Value
+		if !strings.EqualFold(name, "id") && !strings.EqualFold(name, entity+"Id") {
+			continue
+		}
+		if v, ok := args[name]; ok && v != nil {
+			return fmt.Sprint(v), true
+		}
+	}
+	return "", false
+}
+
+func (m *graphQLMock) resolveQuery(f *ast.FieldDefinition) graphql.FieldResolveFn {
+	typeName, list := unwrap(f.Type)
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		key := m.queryName + "." + f.Name.Value
+		if !m.isComposite(typeName) {
+			return m.generateLeaf(typeName, f.Name.Value, key+argsKey(p.Args), list), nil
+		}
+
+		if list {
+			if m.store.HasBeenWritten(typeName) {
+				return pageItems(m.store.List(typeName), p.Args), nil
+			}
+			return m.generateList(typeName, key+argsKey(p.Args), p.Args), nil
+		}
+
+		// user(id: "42") reads the store, else generates user 42
+		if id, ok := idArg(f, typeName, p.Args); ok {
+			if m.hasID(typeName) {
+				return m.lookup(typeName, id), nil
+			}
+			obj := m.generate(typeName, typeName+"#"+id)
+			if _, ok := obj["id"]; ok {
+				obj["id"] = id
+			}
+			return obj, nil
+		}
+		return m.generate(typeName, key+argsKey(p.Args)), nil
+	}
+}
+
+// resolveField resolves a field of a generated or stored object. scalars were
+// generated with the object; nested objects are generated on demand
+func (m *graphQLMock) resolveField(parentType string, f *ast.FieldDefinition) graphql.FieldResolveFn {
+	typeName, list := unwrap(f.Type)
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		source, _ := p.Source.(map[string]interface{})
+		if v, ok := source[f.Name.Value]; ok {
+			return v, nil
+		}
+		key := parentType + "." + f.Name.Value
+		if k, ok := source["__key"].(string); ok {
+			key = k + "." + f.Name.Value
+		}
+		if id, ok := source["id"]; ok {
+			key = parentType + "#" + fmt.Sprint(id) + "." + f.Name.Value
+		}
+		key += argsKey(p.Args)
+
+		if !m.isComposite(typeName) {
+			return m.generateLeaf(typeName, f.Name.Value, key, list), nil
+		}
+		if !list {
+			// author + authorId: follow the reference
+			if ref, ok := source[f.Name.Value+"Id"]; ok && ref != nil && m.hasID(typeName) {
+				return m.lookup(typeName, fmt.Sprint(ref)), nil
+			}
+			return m.generate(typeName, key), nil
+		}
+		if m.store.HasBeenWritten(typeName) {
+			return pageItems(m.related(typeName, parentType, source["id"]), p.Args), nil
+		}
+		return m.generateList(typeName, key, p.Args), nil
+	}
+}
+
+// related lists stored objects pointing back at a parent through a
+// <parent>Id field (Post.authorId, Post.userId); without one, all of them
+func (m *graphQLMock) related(typeName, parentType string, parentID interface{}) []interface{} {
+	items := m.store.List(typeName)
+	if parentID == nil {
+		return items
+	}
+	obj, _ := m.defs[typeName].(*ast.ObjectDefinition)
+	if obj == nil {
+		return items
+	}
+	var refField string
+	for _, f := range obj.Fields {
+		name := f.Name.Value
+		if strings.EqualFold(name, parentType+"Id") || strings.EqualFold(name, parentType+"_id") {
+			refField = name
+			break
+		}
+	}
+	if refField == "" {
+		return items
+	}
+	var result []interface{}
+	for _, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok && fmt.Sprint(obj[refField]) == fmt.Sprint(parentID) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func pageItems(items []interface{}, args map[string]interface{}) []interface{} {
+	// store order is random, ids keep pages stable
+	sort.Slice(items, func(i, j int) bool {
+		a, _ := items[i].(map[string]interface{})
+		b, _ := items[j].(map[string]interface{})
+		return fmt.Sprint(a["id"]) < fmt.Sprint(b["id"])
+	})
+	offset := offsetArg(args)
+	if offset > len(items) {
+		offset = len(items)
+	}
+	items = items[offset:]
+	if n, ok := limitArg(args); ok && n < len(items) {
+		items = items[:n]
+	}
+	return items
+}
+
+// argsKey makes arguments part of the seed, so different filters give
+// different (but repeatable) data
+func argsKey(args map[string]interface{}) string {
+	if len(args) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(args)
+	return string(b)
+}
+
+// resolveMutation picks the behaviour from the field name: create*, update*
+// and delete* (and their synonyms) write to the store, anything else returns
+// generated data
+func (m *graphQLMock) resolveMutation(f *ast.FieldDefinition) graphql.FieldResolveFn {
+	returnType, list := unwrap(f.Type)
+	entity, wrapField := m.entityFor(f)
+	verb := mutationVerb(f.Name.Value)
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		key := m.mutateName + "." + f.Name.Value + argsKey(p.Args)
+		if entity == "" || verb == "" {
+			if !m.isComposite(returnType) {
+				return m.generateLeaf(returnType, f.Name.Value, key, list), nil
+			}
+			return m.generate(returnType, key), nil
+		}
+
+		input := mutationInput(f, p.Args)
+		id, hasID := idArg(f, entity, p.Args)
+		if !hasID {
+			if v, ok := input["id"]; ok && v != nil {
+				id, hasID = fmt.Sprint(v), true
+			}
+		}
+
+		var obj map[string]interface{}
+		switch verb {
+		case "create":
+			if !hasID {
+				id = uuid.NewString()
+			}
+			obj = m.generate(entity, entity+"#"+id)
+			merge(obj, input, m.scalarSchema(entity))
+			obj["id"] = id
+			m.store.Put(entity, id, obj)
+		case "update":
+			if !hasID {
+				return nil, fmt.Errorf("%s needs an id", f.Name.Value)
+			}
+			obj = m.lookup(entity, id)
+			if obj == nil {
+				return nil, fmt.Errorf("%s %s not found", entity, id)
+			}
+			merge(obj, input, m.scalarSchema(entity))
+			obj["id"] = id
+			m.store.Put(entity, id, obj)
+		case "delete":
+			if !hasID {
+				return nil, fmt.Errorf("%s needs an id", f.Name.Value)
+			}
+			obj = m.lookup(entity, id)
+			if obj == nil {
+				return nil, fmt.Errorf("%s %s not found", entity, id)
+			}
+			m.store.Delete(entity, id)
+		}
+
+		switch {
+		case returnType == "Boolean":
+			return true, nil
+		case returnType == "ID" || (returnType == "String" && verb == "delete"):
+			return id, nil
+		case wrapField != "":
+			payload := m.generate(returnType, key)
+			payload[wrapField] = obj
+			return payload, nil
+		case list:
+			return []interface{}{obj}, nil
+		}
+		return obj, nil
+	}
+}
+
+// lookup returns a stored object, or the generated one the client has been
+// reading if nothing has been written yet
+func (m *graphQLMock) lookup(entity, id string) map[string]interface{} {
+	if v, ok := m.store.Get(entity, id); ok {
+		obj, _ := v.(map[string]interface{})
+		return obj
+	}
+	if m.store.HasBeenWritten(entity) {
+		return nil
+	}
+	obj := m.generate(entity, entity+"#"+id)
+	obj["id"] = id
+	return obj
+}
+
+func mutationVerb(name string) string {
+	tokens := nameTokens(name)
+	if len(tokens) == 0 {
+		return ""
+	}
+	switch tokens[0] {
+	case "create", "add", "new", "insert", "register", "post":
+		return "create"
+	case "update", "edit", "modify", "set", "patch", "change", "rename", "upsert":
+		return "update"
+	case "delete", "remove", "destroy", "archive":
+		return "delete"
+	}
+	return ""
+}
+
+// mutationInput flattens a mutation's arguments: createUser(input: {...}) and
+// createUser(name: "x") both give {"name": "x"}
+func mutationInput(f *ast.FieldDefinition, args map[string]interface{}) map[string]interface{} {
+	input := map[string]interface{}{}
+	for _, a := range f.Arguments {
+		v := args[a.Name.Value]
+		if obj, ok := v.(map[string]interface{}); ok {
+			for k, val := range obj {
+				input[k] = val
+			}
+		} else if v != nil {
+			input[a.Name.Value] = v
+		}
+	}
+	return input
+}
+
+// merge copies input onto an object, only for the object's scalar fields
+func merge(obj, input map[string]interface{}, schema *openapi3.Schema) {
+	for k, v := range input {
+		if _, ok := schema.Properties[k]; ok {
+			obj[k] = v
+		}
+	}
+}
+
+// --------------- HTTP ---------------
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+func (m *graphQLMock) handle(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	var req graphQLRequest
+	switch r.Method {
+	case "GET":
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			json.Unmarshal([]byte(v), &req.Variables)
+		}
+	case "POST":
+		body, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/graphql") {
+			req.Query = string(body)
+		} else if err := json.Unmarshal(body, &req); err != nil {
+			writeResponse(w, "application/json", 400, map[string]interface{}{
+				"errors": []map[string]string{{"message": "invalid JSON body: " + err.Error()}},
+			})
+			logRequest(r.Method, r.URL.Path, 400, time.Since(start))
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeResponse(w, "application/json", 405, map[string]interface{}{
+			"errors": []map[string]string{{"message": "use GET or POST"}},
+		})
+		logRequest(r.Method, r.URL.Path, 405, time.Since(start))
+		return
+	}
+	if req.Query == "" {
+		writeResponse(w, "application/json", 400, map[string]interface{}{
+			"errors": []map[string]string{{"message": "missing query"}},
+		})
+		logRequest(r.Method, r.URL.Path, 400, time.Since(start))
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         m.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        r.Context(),
+	})
+
+	// requests that fail validation never executed: 400, like other servers
+	code := 200
+	if result.Data == nil && result.HasErrors() {
+		code = 400
+	}
+	writeResponse(w, "application/json", code, result)
+	logGraphQL(operationLabel(req), code, len(result.Errors), time.Since(start))
+}
+
+// operationLabel is "query GetUser" or "mutation" for the request log
+func operationLabel(req graphQLRequest) string {
+	doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		return "invalid"
+	}
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		name := ""
+		if op.Name != nil {
+			name = op.Name.Value
+		}
+		if req.OperationName != "" && name != req.OperationName {
+			continue
+		}
+		if name == "" && op.SelectionSet != nil {
+			var fields []string
+			for _, sel := range op.SelectionSet.Selections {
+				if f, ok := sel.(*ast.Field); ok {
+					fields = append(fields, f.Name.Value)
+				}
+			}
+			name = "{" + strings.Join(fields, ", ") + "}"
+		}
+		return op.Operation + " " + name
+	}
+	return "unknown"
+}
+
+// routes lists the root fields for the startup banner
+func (m *graphQLMock) routes() []routeInfo {
+	var routes []routeInfo
+	for _, root := range []struct{ typeName, label string }{{m.queryName, "QUERY"}, {m.mutateName, "MUTATE"}} {
+		obj, ok := m.defs[root.typeName].(*ast.ObjectDefinition)
+		if !ok {
+			continue
+		}
+		for _, f := range obj.Fields {
+			routes = append(routes, routeInfo{path: f.Name.Value, methods: []string{root.label}})
+		}
+	}
+	return routes
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const petsSDL = `
+interface Node { id: ID! }
+type Pet implements Node { id: ID! name: String! born: DateTime owner: Owner }
+type Owner implements Node { id: ID! email: String! pets: [Pet!]! }
+scalar DateTime
+type Query { pet(id: ID!): Pet owners: [Owner!]! node(id: ID!): Node }
+`
+
+// resolvers run on every request's goroutine; run with -race
+func TestGraphQLConcurrentGenerate(t *testing.T) {
+	m, err := newGraphQLMock(petsSDL, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for _, typeName := range []string{"Pet", "Owner", "Node"} {
+				obj := m.generate(typeName, fmt.Sprintf("%s:%d", typeName, i))
+				if obj["id"] == nil {
+					t.Errorf("%s has no id: %v", typeName, obj)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestGraphQLCORS(t *testing.T) {
+	defer func(c CORSConfig) { cors = c }(cors)
+	cors = CORSConfig{Origins: []string{"https://app.example.com"}, Credentials: true}
+
+	m, err := newGraphQLMock(petsSDL, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := withCORS(m.handle, "GET", "POST", "OPTIONS")
+
+	preflight := httptest.NewRequest("OPTIONS", "/graphql", nil)
+	preflight.Header.Set("Origin", "https://app.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+	handler(rec, preflight)
+	if rec.Code != 204 || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("preflight: %d %v", rec.Code, rec.Header())
+	}
+
+	query := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ owners { id } }"}`))
+	query.Header.Set("Origin", "https://evil.example.com")
+	query.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	handler(rec, query)
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("disallowed origin: %d %v", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,586 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	stdlog "log"
+	"math/rand"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/getkin/kin-openapi/openapi3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	protoImportPaths []string
+	streamInterval   time.Duration
+)
+
+func runGRPC(cmd *cobra.Command, args []string) error {
+	cfg := loadConfig()
+	applyConfig(cfg)
+	if err := applyConfigRules(cfg); err != nil {
+		return err
+	}
+	if err := applyDataFlags(); err != nil {
+		return err
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	mock, err := newGRPCMock(args, protoImportPaths, seed)
+	if err != nil {
+		return err
+	}
+
+	opts := []grpc.ServerOption{grpc.UnknownServiceHandler(mock.handle)}
+	if tlsOpts.active() {
+		cfg, err := buildTLSConfig()
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+	}
+	srv := grpc.NewServer(opts...)
+	reflectionOpts := reflection.ServerOptions{Services: mock, DescriptorResolver: mock.files, ExtensionResolver: mock.types}
+	reflectionv1.RegisterServerReflectionServer(srv, reflection.NewServerV1(reflectionOpts))
+	reflectionv1alpha.RegisterServerReflectionServer(srv, reflection.NewServer(reflectionOpts))
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(renderBanner("grpc", strings.Join(args, ", "), port, seed, delay, chaos, false))
+	fmt.Print(renderRoutes(mock.routes()))
+	fmt.Print(renderReady(port))
+
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		<-sigCh
+		fmt.Print(renderShutdown())
+		srv.GracefulStop()
+	}()
+
+	return srv.Serve(lis)
+}
+
+// grpcMock answers every method of the compiled services with generated
+// messages. there's no generated code: requests and responses are dynamicpb
+// messages built from the descriptors
+type grpcMock struct {
+	files   *protoregistry.Files
+	types   *protoregistry.Types
+	methods map[string]protoreflect.MethodDescriptor // keyed by /pkg.Service/Method
+	schemas map[protoreflect.FullName]*openapi3.Schema
+	seed    int64
+}
+
+func newGRPCMock(protoFiles, importPaths []string, seed int64) (*grpcMock, error) {
+	names, paths := protoSourcePaths(protoFiles, importPaths)
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: paths}),
+	}
+	compiled, err := compiler.Compile(context.Background(), names...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile protos: %w", err)
+	}
+
+	m := &grpcMock{
+		files:   &protoregistry.Files{},
+		types:   &protoregistry.Types{},
+		methods: map[string]protoreflect.MethodDescriptor{},
+		schemas: map[protoreflect.FullName]*openapi3.Schema{},
+		seed:    seed,
+	}
+	for _, fd := range compiled {
+		m.register(fd)
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			svc := services.Get(i)
+			methods := svc.Methods()
+			for j := 0; j < methods.Len(); j++ {
+				md := methods.Get(j)
+				m.methods["/"+string(svc.FullName())+"/"+string(md.Name())] = md
+				// build every schema up front, handlers only read them
+				m.messageSchema(md.Input())
+				m.messageSchema(md.Output())
+			}
+		}
+	}
+	if len(m.methods) == 0 {
+		return nil, fmt.Errorf("no services found in %s", strings.Join(protoFiles, ", "))
+	}
+	return m, nil
+}
+
+// protoSourcePaths splits the given files into import paths and names
+// relative to them. without -I, each file's own directory is an import path
+func protoSourcePaths(files, importPaths []string) ([]string, []string) {
+	paths := append([]string{}, importPaths...)
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		name := ""
+		for _, dir := range importPaths {
+			if rel, err := filepath.Rel(dir, f); err == nil && !strings.HasPrefix(rel, "..") {
+				name = filepath.ToSlash(rel)
+				break
+			}
+		}
+		if name == "" {
+			dir := filepath.Dir(f)
+			if !containsString(paths, dir) {
+				paths = append(paths, dir)
+			}
+			name = filepath.Base(f)
+		}
+		names = append(names, name)
+	}
+	return names, paths
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// register adds a file and its imports to the registry reflection serves from
+func (m *grpcMock) register(fd protoreflect.FileDescriptor) {
+	if _, err := m.files.FindFileByPath(fd.Path()); err == nil {
+		return
+	}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		m.register(imports.Get(i).FileDescriptor)
+	}
+	m.files.RegisterFile(fd)
+	exts := fd.Extensions()
+	for i := 0; i < exts.Len(); i++ {
+		m.types.RegisterExtension(dynamicpb.NewExtensionType(exts.Get(i)))
+	}
+}
+
+// GetServiceInfo lists the mocked services for server reflection
+func (m *grpcMock) GetServiceInfo() map[string]grpc.ServiceInfo {
+	info := map[string]grpc.ServiceInfo{}
+	for _, md := range m.methods {
+		svc := string(md.Parent().FullName())
+		si := info[svc]
+		si.Methods = append(si.Methods, grpc.MethodInfo{
+			Name:           string(md.Name()),
+			IsClientStream: md.IsStreamingClient(),
+			IsServerStream: md.IsStreamingServer(),
+		})
+		info[svc] = si
+	}
+	return info
+}
+
+// handle serves every RPC: unary and client-streaming calls get one response,
+// server-streaming calls a generated list, bidi calls one response per message
+func (m *grpcMock) handle(_ interface{}, stream grpc.ServerStream) error {
+	start := time.Now()
+	fullMethod, _ := grpc.MethodFromServerStream(stream)
+	md, ok := m.methods[fullMethod]
+	if !ok {
+		logGRPC(fullMethod, codes.Unimplemented, time.Since(start))
+		return status.Errorf(codes.Unimplemented, "unknown method %s", fullMethod)
+	}
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if chaos {
+		chaosRng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		if chaosRng.Float64() < 0.1 {
+			logChaos("GRPC", fullMethod, time.Since(start))
+			return status.Error(codes.Unavailable, "chaos mode struck 💥")
+		}
+		if chaosRng.Float64() < 0.2 {
+			time.Sleep(time.Duration(chaosRng.Intn(2000)) * time.Millisecond)
+		}
+	}
+
+	// x-portblock-status: NOT_FOUND (or 5) forces an error, like Prefer: code=
+	if code, ok := forcedStatus(stream.Context()); ok {
+		logGRPC(fullMethod, code, time.Since(start))
+		return status.Error(code, "forced by x-portblock-status")
+	}
+
+	err := m.serve(stream, md, fullMethod)
+	logGRPC(fullMethod, status.Code(err), time.Since(start))
+	return err
+}
+
+func (m *grpcMock) serve(stream grpc.ServerStream, md protoreflect.MethodDescriptor, fullMethod string) error {
+	recv := func() (*dynamicpb.Message, error) {
+		req := dynamicpb.NewMessage(md.Input())
+		if err := stream.RecvMsg(req); err != nil {
+			return nil, err
+		}
+		return req, nil
+	}
+
+	if md.IsStreamingClient() && md.IsStreamingServer() {
+		for i := 0; ; i++ {
+			req, err := recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := stream.SendMsg(m.response(md, fullMethod, req, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// unary and server-streaming calls get one request; client-streaming
+	// calls are answered after the client is done, based on the last message
+	var req *dynamicpb.Message
+	for {
+		msg, err := recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		req = msg
+		if !md.IsStreamingClient() {
+			break
+		}
+	}
+	if req == nil {
+		req = dynamicpb.NewMessage(md.Input())
+	}
+
+	if !md.IsStreamingServer() {
+		return stream.SendMsg(m.response(md, fullMethod, req, 0))
+	}
+
+	rng := seededRng(m.seed, fullMethod+"#"+requestKey(req)+"#count")
+	count := arrayCountRange(&openapi3.Schema{}).pick(rng)
+	if n, ok := countMetadata(stream.Context()); ok {
+		count = n
+	}
+	for i := 0; i < count; i++ {
+		if i > 0 && streamInterval > 0 {
+			select {
+			case <-stream.Context().Done():
+				return stream.Context().Err()
+			case <-time.After(streamInterval):
+			}
+		}
+		if err := stream.SendMsg(m.response(md, fullMethod, req, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// response generates the output message, seeded by method, request and
+// position so the same call always gets the same answer
+func (m *grpcMock) response(md protoreflect.MethodDescriptor, fullMethod string, req *dynamicpb.Message, i int) proto.Message {
+	rng := seededRng(m.seed, fullMethod+"#"+requestKey(req)+"#"+strconv.Itoa(i))
+	resp := m.generate(md.Output(), rng)
+	echoRequestFields(resp, req)
+	return resp
+}
+
+func requestKey(req *dynamicpb.Message) string {
+	b, _ := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	return string(b)
+}
+
+// generate builds a message by generating JSON through the fake-data engine
+// and reading it back with protojson
+func (m *grpcMock) generate(desc protoreflect.MessageDescriptor, rng *rand.Rand) *dynamicpb.Message {
+	msg := dynamicpb.NewMessage(desc)
+	value := dropNulls(generateObject(m.messageSchema(desc), rng, 0, string(desc.Name())))
+	pruneOneofs(desc, value, rng)
+	data, err := json.Marshal(value)
+	if err != nil {
+		return msg
+	}
+	opts := protojson.UnmarshalOptions{DiscardUnknown: true, Resolver: m.types}
+	if err := opts.Unmarshal(data, msg); err != nil {
+		stdlog.Printf("warning: could not build %s: %v", desc.FullName(), err)
+		return dynamicpb.NewMessage(desc)
+	}
+	return msg
+}
+
+// dropNulls removes what the generator gave up on (recursive messages past
+// its depth limit), which protojson won't take for scalars and list items
+func dropNulls(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if item == nil {
+				delete(val, k)
+			} else {
+				val[k] = dropNulls(item)
+			}
+		}
+	case []interface{}:
+		kept := val[:0]
+		for _, item := range val {
+			if item != nil {
+				kept = append(kept, dropNulls(item))
+			}
+		}
+		return kept
+	}
+	return v
+}
+
+// echoRequestFields copies top-level fields the request and response share
+// (id, name, ...) so GetUser(id: 42) returns user 42
+func echoRequestFields(resp, req *dynamicpb.Message) {
+	reqFields := req.Descriptor().Fields()
+	respFields := resp.Descriptor().Fields()
+	for i := 0; i < reqFields.Len(); i++ {
+		rf := reqFields.Get(i)
+		if rf.IsList() || rf.IsMap() || rf.Message() != nil || !req.Has(rf) {
+			continue
+		}
+		out := respFields.ByName(rf.Name())
+		if out == nil || out.Kind() != rf.Kind() || out.IsList() || out.IsMap() {
+			continue
+		}
+		resp.Set(out, req.Get(rf))
+	}
+}
+
+// messageSchema describes a message as an OpenAPI schema in protojson's shape.
+// schemas are cached by name, which also ties off recursive messages. they're
+// all built in newGRPCMock, since each stream runs on its own goroutine
+func (m *grpcMock) messageSchema(desc protoreflect.MessageDescriptor) *openapi3.Schema {
+	if s, ok := m.schemas[desc.FullName()]; ok {
+		return s
+	}
+	if s := wellKnownSchema(desc.FullName()); s != nil {
+		return s
+	}
+	schema := openapi3.NewObjectSchema()
+	schema.Title = string(desc.Name())
+	m.schemas[desc.FullName()] = schema
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Message() != nil && opaqueMessage(fd.Message().FullName()) {
+			continue
+		}
+		var prop *openapi3.Schema
+		switch {
+		case fd.IsMap():
+			prop = openapi3.NewObjectSchema().WithAdditionalProperties(m.fieldSchema(fd.MapValue()))
+		case fd.IsList():
+			prop = openapi3.NewArraySchema().WithItems(m.fieldSchema(fd))
+		default:
+			prop = m.fieldSchema(fd)
+		}
+		schema.Properties[string(fd.Name())] = openapi3.NewSchemaRef("", prop)
+	}
+	return schema
+}
+
+func (m *grpcMock) fieldSchema(fd protoreflect.FieldDescriptor) *openapi3.Schema {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return openapi3.NewBoolSchema()
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return openapi3.NewInt32Schema()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return openapi3.NewInt32Schema().WithMin(0)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return openapi3.NewInt64Schema()
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return openapi3.NewInt64Schema().WithMin(0)
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return openapi3.NewFloat64Schema()
+	case protoreflect.BytesKind:
+		return openapi3.NewBytesSchema()
+	case protoreflect.StringKind:
+		if name := string(fd.Name()); name == "id" || strings.HasSuffix(name, "_id") {
+			return openapi3.NewUUIDSchema()
+		}
+	case protoreflect.EnumKind:
+		s := openapi3.NewStringSchema()
+		values := fd.Enum().Values()
+		for i := 0; i < values.Len(); i++ {
+			// skip the zero value when there's something better: FOO_UNSPECIFIED
+			if values.Get(i).Number() == 0 && values.Len() > 1 {
+				continue
+			}
+			s.Enum = append(s.Enum, string(values.Get(i).Name()))
+		}
+		return s
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return m.messageSchema(fd.Message())
+	}
+	return openapi3.NewStringSchema()
+}
+
+// wellKnownSchema maps google.protobuf types to their JSON form
+func wellKnownSchema(name protoreflect.FullName) *openapi3.Schema {
+	switch name {
+	case "google.protobuf.Timestamp":
+		return openapi3.NewDateTimeSchema()
+	case "google.protobuf.Duration":
+		return openapi3.NewStringSchema().WithPattern(`^[1-9][0-9]{0,2}s$`)
+	case "google.protobuf.Empty", "google.protobuf.Struct":
+		return openapi3.NewObjectSchema()
+	case "google.protobuf.StringValue":
+		return openapi3.NewStringSchema()
+	case "google.protobuf.BytesValue":
+		return openapi3.NewBytesSchema()
+	case "google.protobuf.BoolValue":
+		return openapi3.NewBoolSchema()
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return openapi3.NewInt32Schema()
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return openapi3.NewInt64Schema()
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return openapi3.NewFloat64Schema()
+	}
+	return nil
+}
+
+// opaqueMessage reports types there's no sensible fake value for; they stay unset
+func opaqueMessage(name protoreflect.FullName) bool {
+	switch name {
+	case "google.protobuf.Any", "google.protobuf.Value", "google.protobuf.ListValue", "google.protobuf.FieldMask":
+		return true
+	}
+	return false
+}
+
+// pruneOneofs keeps one member of each oneof, since protojson rejects several
+func pruneOneofs(desc protoreflect.MessageDescriptor, value interface{}, rng *rand.Rand) {
+	obj, ok := value.(map[string]interface{})
+	if !ok || wellKnownSchema(desc.FullName()) != nil {
+		return
+	}
+	oneofs := desc.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		oo := oneofs.Get(i)
+		if oo.IsSynthetic() {
+			continue
+		}
+		fields := oo.Fields()
+		keep := rng.Intn(fields.Len())
+		for j := 0; j < fields.Len(); j++ {
+			if j != keep {
+				delete(obj, string(fields.Get(j).Name()))
+			}
+		}
+	}
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Message() == nil || fd.IsMap() {
+			continue
+		}
+		v := obj[string(fd.Name())]
+		if list, ok := v.([]interface{}); ok && fd.IsList() {
+			for _, item := range list {
+				pruneOneofs(fd.Message(), item, rng)
+			}
+		} else {
+			pruneOneofs(fd.Message(), v, rng)
+		}
+	}
+}
+
+// forcedStatus reads the x-portblock-status metadata: a code name or number
+func forcedStatus(ctx context.Context) (codes.Code, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("x-portblock-status")
+	if len(values) == 0 {
+		return codes.OK, false
+	}
+	v := strings.TrimSpace(values[0])
+	if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 16 {
+		return codes.Code(n), true
+	}
+	var code codes.Code
+	if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(v)))); err == nil && code != codes.OK {
+		return code, true
+	}
+	return codes.OK, false
+}
+
+// countMetadata reads x-portblock-count, the number of streamed messages
+func countMetadata(ctx context.Context) (int, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("x-portblock-count")
+	if len(values) == 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(values[0])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	if n > maxListSize {
+		n = maxListSize
+	}
+	return n, true
+}
+
+// routes lists the methods for the startup banner
+func (m *grpcMock) routes() []routeInfo {
+	names := make([]string, 0, len(m.methods))
+	for name := range m.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	routes := make([]routeInfo, 0, len(names))
+	for _, name := range names {
+		md := m.methods[name]
+		kind := "UNARY"
+		switch {
+		case md.IsStreamingClient() && md.IsStreamingServer():
+			kind = "BIDI"
+		case md.IsStreamingServer():
+			kind = "STREAM"
+		case md.IsStreamingClient():
+			kind = "CLIENT"
+		}
+		routes = append(routes, routeInfo{path: name, methods: []string{kind}})
+	}
+	return routes
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const petsProto = `syntax = "proto3";
+package pets;
+import "google/protobuf/timestamp.proto";
+
+message Pet {
+  string id = 1;
+  string name = 2;
+  google.protobuf.Timestamp born = 3;
+  repeated Pet friends = 4;
+  map<string, Owner> owners = 5;
+}
+message Owner { string email = 1; }
+message GetPetRequest { string id = 1; }
+
+service Pets {
+  rpc GetPet(GetPetRequest) returns (Pet);
+}
+`
+
+// each stream runs on its own goroutine; run with -race
+func TestGRPCConcurrentGenerate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pets.proto")
+	if err := os.WriteFile(file, []byte(petsProto), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := newGRPCMock([]string{file}, nil, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := m.methods["/pets.Pets/GetPet"]
+	if md == nil {
+		t.Fatalf("GetPet not registered: %v", m.methods)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			msg := m.generate(md.Output(), rand.New(rand.NewSource(int64(i))))
+			if !msg.Has(md.Output().Fields().ByName("id")) {
+				t.Errorf("generated Pet has no id")
+			}
+		}(i)
+	}
+	wg.Wait()
+}
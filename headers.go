@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// declaredHeaderWriter adds the response headers an operation declares for
+// whichever status code the handler ends up writing
+type declaredHeaderWriter struct {
+	http.ResponseWriter
+	server      *MockServer
+	r           *http.Request
+	op          *openapi3.Operation
+	wroteHeader bool
+}
+
+func (dw *declaredHeaderWriter) WriteHeader(code int) {
+	if !dw.wroteHeader {
+		dw.wroteHeader = true
+		dw.server.writeDeclaredHeaders(dw.Header(), dw.r, dw.op, code)
+	}
+	dw.ResponseWriter.WriteHeader(code)
+}
+
+func (dw *declaredHeaderWriter) Write(b []byte) (int, error) {
+	if !dw.wroteHeader {
+		dw.WriteHeader(http.StatusOK)
+	}
+	return dw.ResponseWriter.Write(b)
+}
+
+func (dw *declaredHeaderWriter) Flush() {
+	if f, ok := dw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (dw *declaredHeaderWriter) Unwrap() http.ResponseWriter {
+	return dw.ResponseWriter
+}
+
+// writeDeclaredHeaders generates every header declared on the response for
+// code. headers the handler already set (Location, Content-Type) are kept
+func (s *MockServer) writeDeclaredHeaders(h http.Header, r *http.Request, op *openapi3.Operation, code int) {
+	if op == nil || op.Responses == nil {
+		return
+	}
+	resp := op.Responses.Status(code)
+	if resp == nil && (code < 400 || !hasSuccessResponse(op)) {
+		resp = op.Responses.Default()
+	}
+	if resp == nil || resp.Value == nil || len(resp.Value.Headers) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(resp.Value.Headers))
+	for name := range resp.Value.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rng := seededRng(s.seed, fmt.Sprintf("%s#headers%d", r.URL.Path, code))
+	limit := 100 * (1 + rng.Intn(10))
+
+	for _, name := range names {
+		// Content-Type in a headers map is ignored per the OpenAPI spec
+		if strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		ref := resp.Value.Headers[name]
+		if ref == nil || ref.Value == nil {
+			continue
+		}
+		if h.Get(name) == "" {
+			value := generateHeaderValue(name, ref.Value, rng, limit)
+			if value != nil {
+				h.Set(name, formatHeaderValue(value))
+			}
+			if strictMode && ref.Value.Schema != nil && value != nil {
+				for _, warn := range validateResponseAgainstSchema(ref.Value.Schema, value, "header "+name) {
+					logStrictWarning("response", warn)
+				}
+			}
+		}
+		if strictMode && ref.Value.Required && h.Get(name) == "" {
+			logStrictWarning("response", fmt.Sprintf("%s %s: missing required header '%s'", r.Method, r.URL.Path, name))
+		}
+	}
+}
+
+// generateHeaderValue knows the usual suspects (rate limits, request ids,
+// caching headers) and falls back to the header's schema
+func generateHeaderValue(name string, header *openapi3.Header, rng *rand.Rand, limit int) interface{} {
+	var schema *openapi3.Schema
+	if header.Schema != nil {
+		schema = header.Schema.Value
+	}
+	if header.Example != nil {
+		return header.Example
+	}
+	if schema != nil && (schema.Example != nil || len(schema.Enum) > 0) {
+		return generateFromSchema(header.Schema, rng, 0)
+	}
+
+	isString := schema == nil || schema.Type.Is("string") || schema.Type.Slice() == nil
+	key := strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(name))
+	switch {
+	case strings.Contains(key, "ratelimitlimit"):
+		return limit
+	case strings.Contains(key, "ratelimitremaining"):
+		return rng.Intn(limit + 1)
+	case strings.Contains(key, "ratelimitreset"):
+		if schema != nil && schema.Format == "date-time" {
+			return currentNow().Add(time.Duration(1+rng.Intn(3600)) * time.Second).Format(time.RFC3339)
+		}
+		return 1 + rng.Intn(3600)
+	case key == "retryafter":
+		return 1 + rng.Intn(120)
+	case isString && (strings.Contains(key, "requestid") || strings.Contains(key, "correlationid") || strings.Contains(key, "traceid")):
+		return gofakeit.New(uint64(rng.Int63())).UUID()
+	case isString && key == "etag":
+		return fmt.Sprintf(`"%016x"`, rng.Uint64())
+	case isString && key == "lastmodified":
+		return randomPastTime(rng).Format(http.TimeFormat)
+	}
+
+	if header.Schema == nil {
+		return nil
+	}
+	return generateFromSchemaWithName(header.Schema, rng, 0, name, "", nil)
+}
+
+func formatHeaderValue(v interface{}) string {
+	switch val := v.(type) {
+	case []interface{}:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = fmt.Sprintf("%v", item)
+		}
+		return strings.Join(parts, ",")
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	stdlog "log"
@@ -15,6 +15,7 @@ import (
 	"os"
 	"os/signal"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,11 +23,11 @@ import (
 	"time"
 
 	"github.com/brianvoe/gofakeit/v7"
-	"github.com/fsnotify/fsnotify"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/getkin/kin-openapi/routers"
 	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gorilla/websocket"
 	"github.com/spf13/cobra"
 )
 
@@ -36,6 +37,7 @@ var (
 	delay   time.Duration
 	chaos   bool
 	noAuth  bool
+	nowFlag string
 	version = "0.5.0"
 )
 
@@ -47,9 +49,9 @@ func main() {
 	}
 
 	serveCmd := &cobra.Command{
-		Use:   "serve [spec-file]",
-		Short: "start a mock server from an OpenAPI spec",
-		Args:  cobra.ExactArgs(1),
+		Use:   "serve [[mount=]spec-file...]",
+		Short: "start a mock server from one or more OpenAPI specs",
+		Args:  cobra.ArbitraryArgs,
 		RunE:  runServe,
 	}
 
@@ -62,6 +64,20 @@ func main() {
 	serveCmd.Flags().StringVar(&webhookTarget, "webhook-target", "", "URL to send webhooks to on mutations")
 	serveCmd.Flags().DurationVar(&webhookDelay, "webhook-delay", 0, "delay before sending webhooks (e.g. 500ms)")
 
+	serveCmd.Flags().StringVar(&listSizeFlag, "list-size", "", "items per generated array, N or MIN-MAX (default 2-5)")
+	serveCmd.Flags().StringVar(&nowFlag, "now", "", "clock generated dates are relative to: RFC3339, YYYY-MM-DD or \"now\" for the wall clock (default: 2026-01-01)")
+	serveCmd.Flags().DurationVar(&sseInterval, "sse-interval", time.Second, "time between generated server-sent events")
+	serveCmd.Flags().StringVar(&asyncAPIFile, "asyncapi", "", "AsyncAPI document whose channels are served as WebSockets")
+	serveCmd.Flags().DurationVar(&wsInterval, "ws-interval", time.Second, "time between generated WebSocket messages")
+	serveCmd.Flags().StringSliceVar(&cors.Origins, "cors-origin", nil, "allowed CORS origins, repeatable, wildcards like https://*.example.com (default: any)")
+	serveCmd.Flags().BoolVar(&cors.Credentials, "cors-credentials", false, "allow credentialed CORS requests (echoes the origin instead of *)")
+	serveCmd.Flags().StringVar(&cors.Fail, "cors-fail", "", "simulate a CORS failure: preflight, missing, origin or credentials")
+	serveCmd.Flags().StringVar(&basePathFlag, "base-path", "", "serve the spec under this path prefix instead of the servers' base paths")
+	serveCmd.Flags().BoolVar(&noCompress, "no-compress", false, "never compress responses, whatever Accept-Encoding says")
+	serveCmd.Flags().StringVar(&errorFormat, "error-format", "", "body for mock-generated errors without a spec schema: problem or simple (default: problem)")
+
+	addTLSFlags(serveCmd)
+
 	var watch bool
 	serveCmd.Flags().BoolVar(&watch, "watch", true, "watch spec file for changes and hot reload")
 
@@ -77,6 +93,7 @@ func main() {
 	proxyCmd.Flags().StringVar(&proxyTarget, "target", "", "target base URL to proxy to (required)")
 	proxyCmd.Flags().StringVar(&proxyRecord, "record", "", "file to record responses to")
 	proxyCmd.Flags().BoolVar(&strictMode, "strict", false, "strict mode — reject invalid specs, validate responses")
+	addTLSFlags(proxyCmd)
 	proxyCmd.MarkFlagRequired("target")
 
 	replayCmd := &cobra.Command{
@@ -86,6 +103,7 @@ func main() {
 		RunE:  runReplay,
 	}
 	replayCmd.Flags().IntVarP(&port, "port", "p", 4000, "port to listen on")
+	addTLSFlags(replayCmd)
 
 	diffCmd := &cobra.Command{
 		Use:   "diff [spec-file]",
@@ -127,7 +145,54 @@ func main() {
 	testCmd.Flags().StringVar(&testTarget, "target", "", "target base URL to test against (default: spin up internal mock)")
 	testCmd.Flags().BoolVar(&testVerbose, "verbose", false, "show full request/response dumps")
 
-	rootCmd.AddCommand(serveCmd, proxyCmd, replayCmd, diffCmd, initCmd, generateCmd, testCmd)
+	graphqlCmd := &cobra.Command{
+		Use:   "graphql [schema-file]",
+		Short: "start a GraphQL mock server from an SDL schema",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runGraphQL,
+	}
+	graphqlCmd.Flags().IntVarP(&port, "port", "p", 4000, "port to listen on")
+	graphqlCmd.Flags().Int64Var(&seed, "seed", 0, "random seed for reproducible data (0 = random)")
+	graphqlCmd.Flags().DurationVar(&delay, "delay", 0, "simulated latency per request (e.g. 200ms)")
+	graphqlCmd.Flags().StringVar(&listSizeFlag, "list-size", "", "items per generated list, N or MIN-MAX (default 2-5)")
+	graphqlCmd.Flags().StringVar(&nowFlag, "now", "", "clock generated dates are relative to: RFC3339, YYYY-MM-DD or \"now\" for the wall clock (default: 2026-01-01)")
+	graphqlCmd.Flags().StringVar(&graphqlPath, "path", "/graphql", "endpoint path")
+	graphqlCmd.Flags().StringSliceVar(&cors.Origins, "cors-origin", nil, "allowed CORS origins, repeatable, wildcards like https://*.example.com (default: any)")
+	graphqlCmd.Flags().BoolVar(&cors.Credentials, "cors-credentials", false, "allow credentialed CORS requests (echoes the origin instead of *)")
+	graphqlCmd.Flags().StringVar(&cors.Fail, "cors-fail", "", "simulate a CORS failure: preflight, missing, origin or credentials")
+	addTLSFlags(graphqlCmd)
+
+	grpcCmd := &cobra.Command{
+		Use:   "grpc [proto-files...]",
+		Short: "start a gRPC mock server from .proto files",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runGRPC,
+	}
+	grpcCmd.Flags().IntVarP(&port, "port", "p", 4000, "port to listen on")
+	grpcCmd.Flags().Int64Var(&seed, "seed", 0, "random seed for reproducible data (0 = random)")
+	grpcCmd.Flags().DurationVar(&delay, "delay", 0, "simulated latency per call (e.g. 200ms)")
+	grpcCmd.Flags().BoolVar(&chaos, "chaos", false, "chaos mode — random UNAVAILABLE errors and latency spikes")
+	grpcCmd.Flags().StringVar(&listSizeFlag, "list-size", "", "messages per server stream, N or MIN-MAX (default 2-5)")
+	grpcCmd.Flags().StringVar(&nowFlag, "now", "", "clock generated dates are relative to: RFC3339, YYYY-MM-DD or \"now\" for the wall clock (default: 2026-01-01)")
+	grpcCmd.Flags().DurationVar(&streamInterval, "stream-interval", 0, "time between server-streamed messages")
+	grpcCmd.Flags().StringSliceVarP(&protoImportPaths, "import-path", "I", nil, "directories to resolve proto imports from")
+	addTLSFlags(grpcCmd)
+
+	soapCmd := &cobra.Command{
+		Use:   "soap [wsdl-file]",
+		Short: "start a SOAP mock server from a WSDL",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runSOAP,
+	}
+	soapCmd.Flags().IntVarP(&port, "port", "p", 4000, "port to listen on")
+	soapCmd.Flags().Int64Var(&seed, "seed", 0, "random seed for reproducible data (0 = random)")
+	soapCmd.Flags().DurationVar(&delay, "delay", 0, "simulated latency per request (e.g. 200ms)")
+	soapCmd.Flags().BoolVar(&chaos, "chaos", false, "chaos mode — random server faults and latency spikes")
+	soapCmd.Flags().StringVar(&listSizeFlag, "list-size", "", "items per repeated element, N or MIN-MAX (default 2-5)")
+	soapCmd.Flags().StringVar(&nowFlag, "now", "", "clock generated dates are relative to: RFC3339, YYYY-MM-DD or \"now\" for the wall clock (default: 2026-01-01)")
+	addTLSFlags(soapCmd)
+
+	rootCmd.AddCommand(serveCmd, proxyCmd, replayCmd, diffCmd, initCmd, generateCmd, testCmd, graphqlCmd, grpcCmd, soapCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -135,110 +200,115 @@ func main() {
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
-	specFile := args[0]
-
 	// load config file, CLI flags override
 	cfg := loadConfig()
 	applyConfig(cfg)
 	applyConfigWatch(cfg, cmd)
+	if err := applyConfigRules(cfg); err != nil {
+		return err
+	}
+	if err := applyDataFlags(); err != nil {
+		return err
+	}
+	if err := validateCORS(cors); err != nil {
+		return err
+	}
+	format, err := parseErrorFormat(errorFormat)
+	if err != nil {
+		return err
+	}
+	errorFormat = format
 
 	watchFlag, _ := cmd.Flags().GetBool("watch")
 
-	loader := openapi3.NewLoader()
-	doc, err := loader.LoadFromFile(specFile)
+	mounts, err := parseSpecMounts(args, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to load spec: %w", err)
+		return err
 	}
 
-	if err := strictValidateSpec(doc); err != nil {
-		return err
+	// an AsyncAPI document on its own serves only WebSocket channels
+	if isAsyncAPIFile(mounts[0].file) {
+		if asyncAPIFile == "" {
+			asyncAPIFile = mounts[0].file
+		}
+		if len(mounts) == 1 {
+			watchFlag = false
+		}
+	}
+	var async *AsyncAPI
+	if asyncAPIFile != "" {
+		async, err = loadAsyncAPI(asyncAPIFile)
+		if err != nil {
+			return fmt.Errorf("failed to load AsyncAPI document: %w", err)
+		}
 	}
 
 	if seed == 0 {
 		seed = time.Now().UnixNano()
 	}
 
-	router, err := gorillamux.NewRouter(doc)
-	if err != nil {
-		stdlog.Printf("warning: could not build validation router: %v", err)
+	// every spec gets its own store; AsyncAPI channels go with the first one
+	var ports []int
+	byPort := map[int][]*specMount{}
+	for i, m := range mounts {
+		channels := async
+		if i > 0 {
+			channels = nil
+		}
+		name := ""
+		if len(mounts) > 1 {
+			name = m.name
+		}
+		if m.server, err = loadMockServer(m, name, channels); err != nil {
+			return err
+		}
+		if byPort[m.port] == nil {
+			ports = append(ports, m.port)
+		}
+		byPort[m.port] = append(byPort[m.port], m)
 	}
+	sort.Ints(ports)
 
-	webhookMgr := NewWebhookManager(webhookTarget, webhookDelay, doc)
-
-	server := &MockServer{
-		doc:        doc,
-		store:      NewStore(),
-		seed:       seed,
-		router:     router,
-		noAuth:     noAuth,
-		webhookMgr: webhookMgr,
+	var srvs []*http.Server
+	for _, p := range ports {
+		srv := &http.Server{Addr: fmt.Sprintf(":%d", p), Handler: compressHandler(newSpecMux(byPort[p], mounts))}
+		for _, m := range byPort[p] {
+			done := m.server.done
+			srv.RegisterOnShutdown(func() { close(done) })
+		}
+		if err := configureTLS(srv); err != nil {
+			return err
+		}
+		srvs = append(srvs, srv)
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", server.handleRequest)
-
-	addr := fmt.Sprintf(":%d", port)
-	srv := &http.Server{Addr: addr, Handler: mux}
-
 	// render banner
-	fmt.Println(renderBanner("serve", specFile, port, seed, delay, chaos, noAuth))
-
-	// collect and render routes
-	printRoutes(doc)
-	fmt.Print(renderReady(port))
+	if len(mounts) == 1 {
+		m := mounts[0]
+		fmt.Println(renderBanner("serve", m.file, m.port, seed, delay, chaos, noAuth))
+		if len(m.server.doc.Paths.Map()) > 0 {
+			printRoutes(m.server.doc, m.prefix)
+		}
+	} else {
+		fmt.Println(renderBanner("serve", fmt.Sprintf("%d specs", len(mounts)), port, seed, delay, chaos, noAuth))
+		for _, m := range mounts {
+			fmt.Print(renderMount(m.name, m.url()))
+			printRoutes(m.server.doc, m.prefix)
+		}
+	}
+	printChannels(async, mounts[0].prefix+primaryBasePath(mounts[0].server.doc))
+	for _, p := range ports {
+		fmt.Print(renderReady(p))
+	}
 
 	// hot reload watcher
 	if watchFlag {
-		watcher, err := fsnotify.NewWatcher()
-		if err != nil {
-			stdlog.Printf("warning: could not start file watcher: %v", err)
-		} else {
-			if err := watcher.Add(specFile); err != nil {
-				stdlog.Printf("warning: could not watch %s: %v", specFile, err)
-			} else {
-				go func() {
-					var debounce <-chan time.Time
-					for {
-						select {
-						case event, ok := <-watcher.Events:
-							if !ok {
-								return
-							}
-							if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
-								debounce = time.After(200 * time.Millisecond)
-							}
-						case <-debounce:
-							newLoader := openapi3.NewLoader()
-							newDoc, err := newLoader.LoadFromFile(specFile)
-							if err != nil {
-								logReloadError(err)
-								continue
-							}
-							if err := newDoc.Validate(context.Background()); err != nil {
-								logReloadError(err)
-								continue
-							}
-							newRouter, err := gorillamux.NewRouter(newDoc)
-							if err != nil {
-								logReloadError(err)
-								continue
-							}
-							server.mu.Lock()
-							server.doc = newDoc
-							server.router = newRouter
-							server.mu.Unlock()
-							logReload(specFile)
-							printRoutes(newDoc)
-						case err, ok := <-watcher.Errors:
-							if !ok {
-								return
-							}
-							stdlog.Printf("watcher error: %v", err)
-						}
-					}
-				}()
-				defer watcher.Close()
+		for _, m := range mounts {
+			if m.file == asyncAPIFile {
+				continue
 			}
+			stop := m.server.watch(m.file, m.source)
+			defer stop()
 		}
 	}
 
@@ -247,16 +317,30 @@ func runServe(cmd *cobra.Command, args []string) error {
 		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 		<-sigCh
 		fmt.Print(renderShutdown())
-		srv.Shutdown(context.Background())
+		for _, srv := range srvs {
+			srv.Shutdown(context.Background())
+		}
 	}()
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		return err
+	errs := make(chan error, len(srvs))
+	for _, srv := range srvs {
+		go func() { errs <- serveHTTP(srv) }()
+	}
+	for range srvs {
+		if err := <-errs; err != nil {
+			for _, srv := range srvs {
+				srv.Close()
+			}
+			return err
+		}
 	}
 	return nil
 }
 
-func printRoutes(doc *openapi3.T) {
+// printRoutes lists the spec's paths under the prefix and base path they're
+// served at
+func printRoutes(doc *openapi3.T, prefix string) {
+	base := prefix + primaryBasePath(doc)
 	var routes []routeInfo
 	for path, pathItem := range doc.Paths.Map() {
 		methods := []string{}
@@ -275,7 +359,21 @@ func printRoutes(doc *openapi3.T) {
 		if pathItem.Delete != nil {
 			methods = append(methods, "DELETE")
 		}
-		routes = append(routes, routeInfo{path: path, methods: methods})
+		routes = append(routes, routeInfo{path: base + path, methods: methods})
+	}
+	webhooks := specWebhooks(doc)
+	names := make([]string, 0, len(webhooks))
+	for name := range webhooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		methods := []string{}
+		for method := range webhooks[name].Operations() {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		routes = append(routes, routeInfo{path: "webhook " + name, methods: methods})
 	}
 	fmt.Print(renderRoutes(routes))
 }
@@ -286,12 +384,15 @@ type Store struct {
 	mu      sync.RWMutex
 	data    map[string]map[string]interface{}
 	written map[string]bool
+	files   map[string]*storedFile
+	subs    map[chan storeEvent]struct{}
 }
 
 func NewStore() *Store {
 	return &Store{
 		data:    make(map[string]map[string]interface{}),
 		written: make(map[string]bool),
+		files:   make(map[string]*storedFile),
 	}
 }
 
@@ -329,8 +430,13 @@ func (s *Store) Put(resource, id string, obj interface{}) {
 	if s.data[resource] == nil {
 		s.data[resource] = make(map[string]interface{})
 	}
+	kind := "created"
+	if _, exists := s.data[resource][id]; exists {
+		kind = "updated"
+	}
 	s.data[resource][id] = obj
 	s.written[resource] = true
+	s.publish(storeEvent{Kind: kind, Resource: resource, ID: id, Data: obj})
 }
 
 func (s *Store) Delete(resource, id string) bool {
@@ -340,13 +446,24 @@ func (s *Store) Delete(resource, id string) bool {
 	if col == nil {
 		return false
 	}
-	if _, ok := col[id]; !ok {
+	obj, ok := col[id]
+	if !ok {
 		return false
 	}
 	delete(col, id)
+	s.publish(storeEvent{Kind: "deleted", Resource: resource, ID: id, Data: obj})
 	return true
 }
 
+// Reset drops everything written so far, so responses are generated again
+func (s *Store) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = make(map[string]map[string]interface{})
+	s.written = make(map[string]bool)
+	s.files = make(map[string]*storedFile)
+}
+
 // --------------- MockServer ---------------
 
 type MockServer struct {
@@ -355,42 +472,22 @@ type MockServer struct {
 	store      *Store
 	seed       int64
 	router     routers.Router
+	routes     *routeTable
+	prefix     string   // where the spec is mounted when serving several
+	basePaths  []string // stripped off request paths, longest first
 	noAuth     bool
 	webhookMgr *WebhookManager
+	propOrder  propertyOrder
+	async      *AsyncAPI     // WebSocket channels, nil without --asyncapi
+	done       chan struct{} // closed on shutdown to end long-lived streams
 }
 
-var pathParamRe = regexp.MustCompile(`\{([^}]+)\}`)
-
 func (s *MockServer) findRoute(reqPath, reqMethod string) (*openapi3.PathItem, *openapi3.Operation, map[string]string) {
-	for pattern, pathItem := range s.doc.Paths.Map() {
-		params := matchPath(pattern, reqPath)
-		if params == nil {
-			continue
-		}
-		op := getOperation(pathItem, reqMethod)
-		if op != nil {
-			return pathItem, op, params
-		}
+	node, params := s.routes.lookup(reqPath, reqMethod)
+	if node == nil {
+		return nil, nil, nil
 	}
-	return nil, nil, nil
-}
-
-func matchPath(pattern, actual string) map[string]string {
-	regexStr := "^" + pathParamRe.ReplaceAllString(pattern, `([^/]+)`) + "$"
-	re, err := regexp.Compile(regexStr)
-	if err != nil {
-		return nil
-	}
-	matches := re.FindStringSubmatch(actual)
-	if matches == nil {
-		return nil
-	}
-	paramNames := pathParamRe.FindAllStringSubmatch(pattern, -1)
-	params := make(map[string]string)
-	for i, name := range paramNames {
-		params[name[1]] = matches[i+1]
-	}
-	return params
+	return node.item, getOperation(node.item, reqMethod), params
 }
 
 func getOperation(item *openapi3.PathItem, method string) *openapi3.Operation {
@@ -415,100 +512,37 @@ func getOperation(item *openapi3.PathItem, method string) *openapi3.Operation {
 
 // --------------- Content Negotiation ---------------
 
-func negotiateContentType(r *http.Request) string {
-	accept := r.Header.Get("Accept")
-	if accept == "" || accept == "*/*" {
-		return "application/json"
-	}
-	for _, part := range strings.Split(accept, ",") {
-		mt := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
-		switch mt {
-		case "application/json", "*/*":
-			return "application/json"
-		case "application/xml", "text/xml":
-			return "application/xml"
-		}
-	}
-	return "" // unsupported
+func writeResponse(w http.ResponseWriter, contentType string, statusCode int, data interface{}) {
+	writeSchemaResponse(w, contentType, statusCode, data, nil, nil)
 }
 
-func writeResponse(w http.ResponseWriter, contentType string, statusCode int, data interface{}) {
+// writeSchemaResponse encodes data for the negotiated content type. the schema
+// drives XML element names, attributes and property order
+func writeSchemaResponse(w http.ResponseWriter, contentType string, statusCode int, data interface{}, schema *openapi3.SchemaRef, order propertyOrder) {
 	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(statusCode)
 	if data == nil {
 		return
 	}
-	switch contentType {
-	case "application/xml", "text/xml":
-		writeXML(w, data)
+	switch mediaFormat(contentType) {
+	case "xml":
+		writeXMLSchema(w, data, schema, order)
+	case "text":
+		writeText(w, data)
+	case "csv":
+		writeCSV(w, data, schema, order)
 	default:
 		json.NewEncoder(w).Encode(data)
 	}
 }
 
-// XMLMap is a wrapper for encoding maps as XML
-type XMLMap struct {
-	XMLName xml.Name
-	Items   []XMLEntry
-}
-
-type XMLEntry struct {
-	XMLName xml.Name
-	Value   string `xml:",chardata"`
-	Items   []XMLEntry
-}
-
-func writeXML(w io.Writer, data interface{}) {
-	w.Write([]byte(xml.Header))
-	switch v := data.(type) {
-	case map[string]interface{}:
-		w.Write([]byte("<root>"))
-		writeXMLMap(w, v)
-		w.Write([]byte("</root>"))
-	case []interface{}:
-		w.Write([]byte("<root>"))
-		for _, item := range v {
-			w.Write([]byte("<item>"))
-			if m, ok := item.(map[string]interface{}); ok {
-				writeXMLMap(w, m)
-			} else {
-				fmt.Fprintf(w, "%v", item)
-			}
-			w.Write([]byte("</item>"))
-		}
-		w.Write([]byte("</root>"))
-	default:
-		fmt.Fprintf(w, "<root>%v</root>", v)
-	}
-}
-
-func writeXMLMap(w io.Writer, m map[string]interface{}) {
-	for k, v := range m {
-		safe := xml.EscapeText
-		_ = safe
-		fmt.Fprintf(w, "<%s>", k)
-		switch val := v.(type) {
-		case map[string]interface{}:
-			writeXMLMap(w, val)
-		case []interface{}:
-			for _, item := range val {
-				fmt.Fprintf(w, "<item>")
-				if m2, ok := item.(map[string]interface{}); ok {
-					writeXMLMap(w, m2)
-				} else {
-					buf := &bytes.Buffer{}
-					xml.EscapeText(buf, []byte(fmt.Sprintf("%v", item)))
-					w.Write(buf.Bytes())
-				}
-				fmt.Fprintf(w, "</item>")
-			}
-		default:
-			buf := &bytes.Buffer{}
-			xml.EscapeText(buf, []byte(fmt.Sprintf("%v", val)))
-			w.Write(buf.Bytes())
-		}
-		fmt.Fprintf(w, "</%s>", k)
+// respond writes data using the schema the operation declares for code
+func (s *MockServer) respond(w http.ResponseWriter, op *openapi3.Operation, contentType string, code int, data interface{}) {
+	var schema *openapi3.SchemaRef
+	if op != nil {
+		schema = s.getResponseSchema(op, strconv.Itoa(code), contentType)
 	}
+	writeSchemaResponse(w, contentType, code, data, schema, s.propOrder)
 }
 
 // --------------- Auth Simulation ---------------
@@ -569,6 +603,11 @@ func (s *MockServer) checkAuth(w http.ResponseWriter, r *http.Request, op *opena
 				if r.Header.Get("Authorization") == "" {
 					allPassed = false
 				}
+			case "mutualTLS":
+				// the handshake already verified the certificate, if there is one
+				if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+					allPassed = false
+				}
 			}
 			if !allPassed {
 				break
@@ -579,15 +618,13 @@ func (s *MockServer) checkAuth(w http.ResponseWriter, r *http.Request, op *opena
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(401)
-	json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+	s.writeError(w, r, op, mockError{status: 401, detail: "missing or invalid credentials"})
 	return false
 }
 
 // --------------- Request Validation ---------------
 
-func (s *MockServer) validateRequest(w http.ResponseWriter, r *http.Request, body []byte) bool {
+func (s *MockServer) validateRequest(w http.ResponseWriter, r *http.Request, op *openapi3.Operation, body []byte) bool {
 	if s.router == nil {
 		return true
 	}
@@ -613,13 +650,7 @@ func (s *MockServer) validateRequest(w http.ResponseWriter, r *http.Request, bod
 
 	err = openapi3filter.ValidateRequest(context.Background(), input)
 	if err != nil {
-		details := parseValidationError(err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(400)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"error":   "validation failed",
-			"details": details,
-		})
+		s.writeError(w, r, op, mockError{status: 400, detail: "request validation failed", details: parseValidationError(err)})
 		return false
 	}
 
@@ -684,21 +715,28 @@ func parseValidationError(err error) []map[string]string {
 
 // --------------- Prefer Header ---------------
 
-func parsePreferCode(r *http.Request) int {
+// parsePreference returns the value of one preference in the Prefer header,
+// which may list several separated by commas or semicolons
+func parsePreference(r *http.Request, name string) string {
 	prefer := r.Header.Get("Prefer")
 	if prefer == "" {
-		return 0
+		return ""
 	}
-	for _, part := range strings.Split(prefer, ";") {
+	for _, part := range strings.FieldsFunc(prefer, func(c rune) bool { return c == ';' || c == ',' }) {
 		part = strings.TrimSpace(part)
-		if strings.HasPrefix(part, "code=") {
-			code, err := strconv.Atoi(strings.TrimPrefix(part, "code="))
-			if err == nil {
-				return code
-			}
+		if strings.HasPrefix(part, name+"=") {
+			return strings.Trim(strings.TrimPrefix(part, name+"="), `"`)
 		}
 	}
-	return 0
+	return ""
+}
+
+func parsePreferCode(r *http.Request) int {
+	code, err := strconv.Atoi(parsePreference(r, "code"))
+	if err != nil {
+		return 0
+	}
+	return code
 }
 
 func (s *MockServer) handlePreferCode(w http.ResponseWriter, r *http.Request, op *openapi3.Operation, contentType string) bool {
@@ -708,11 +746,11 @@ func (s *MockServer) handlePreferCode(w http.ResponseWriter, r *http.Request, op
 	}
 
 	codeStr := strconv.Itoa(code)
-	schema := s.getResponseSchema(op, codeStr)
+	schema := s.getResponseSchema(op, codeStr, contentType)
 	if schema != nil {
 		rng := seededRng(s.seed, r.URL.Path+codeStr)
 		fake := generateFromSchema(schema, rng, 0)
-		writeResponse(w, contentType, code, fake)
+		s.respond(w, op, contentType, code, fake)
 	} else {
 		w.WriteHeader(code)
 	}
@@ -770,16 +808,31 @@ func applyQueryParams(items []interface{}, query url.Values) []interface{} {
 // --------------- Main Request Handler ---------------
 
 func (s *MockServer) handleRequest(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
 	s.mu.RLock()
+
+	// base path from the servers or --base-path
+	r, mounted := s.mount(r)
+
+	// WebSocket channels from the AsyncAPI document, under the same prefix and
+	// base path. connections are long-lived, so they don't hold the spec lock
+	if mounted && websocket.IsWebSocketUpgrade(r) {
+		if ch := s.async.channel(r.URL.Path); ch != nil {
+			s.mu.RUnlock()
+			s.serveWebSocket(w, r, ch)
+			return
+		}
+	}
 	defer s.mu.RUnlock()
-	start := time.Now()
+
+	if !mounted {
+		s.writeError(w, r, nil, mockError{status: 404, detail: s.unmountedDetail()})
+		logRequest(r.Method, mountedPath(r), 404, time.Since(start))
+		return
+	}
 
 	// CORS
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Prefer, Accept")
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(204)
+	if !s.applyCORS(w, r) {
 		return
 	}
 
@@ -792,10 +845,9 @@ func (s *MockServer) handleRequest(w http.ResponseWriter, r *http.Request) {
 	if chaos {
 		chaosRng := rand.New(rand.NewSource(time.Now().UnixNano()))
 		if chaosRng.Float64() < 0.1 {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(500)
-			json.NewEncoder(w).Encode(map[string]string{"error": "chaos mode struck 💥"})
-			logChaos(r.Method, r.URL.Path, time.Since(start))
+			_, chaosOp, _ := s.findRoute(r.URL.Path, r.Method)
+			s.writeError(w, r, chaosOp, mockError{status: 500, detail: "chaos mode struck 💥"})
+			logChaos(r.Method, mountedPath(r), time.Since(start))
 			return
 		}
 		if chaosRng.Float64() < 0.2 {
@@ -804,61 +856,92 @@ func (s *MockServer) handleRequest(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// content negotiation
-	contentType := negotiateContentType(r)
-	if contentType == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(406)
-		json.NewEncoder(w).Encode(map[string]string{"error": "not acceptable — supported: application/json, application/xml"})
-		logRequest(r.Method, r.URL.Path, 406, time.Since(start))
+	// files uploaded with multipart/form-data
+	if r.Method == "GET" && strings.HasPrefix(r.URL.Path, filesPrefix) {
+		code := s.serveUpload(w, r)
+		logRequest(r.Method, mountedPath(r), code, time.Since(start))
 		return
 	}
 
+	// content negotiation against what the operation declares
 	_, op, params := s.findRoute(r.URL.Path, r.Method)
+	declared := responseMediaTypes(op)
+	contentType := negotiateMediaType(r, declared)
+	if contentType == "" {
+		s.writeError(w, r, op, mockError{status: 406, detail: "not acceptable — supported: " + strings.Join(declared, ", ")})
+		logRequest(r.Method, mountedPath(r), 406, time.Since(start))
+		return
+	}
+	binaryType := ""
+	if _, resp := successResponse(op); isBinaryResponse(resp, contentType) {
+		// errors for binary downloads are still JSON
+		binaryType, contentType = contentType, "application/json"
+	}
+	if strings.Contains(contentType, "*") {
+		contentType = "application/json"
+	}
+
 	if op == nil {
-		writeResponse(w, contentType, 404, map[string]string{"error": "route not found"})
-		logRequest(r.Method, r.URL.Path, 404, time.Since(start))
+		s.writeError(w, r, nil, mockError{status: 404, detail: "route not found"})
+		logRequest(r.Method, mountedPath(r), 404, time.Since(start))
 		return
 	}
 
+	// response headers declared in the spec, for whatever status gets written
+	w = &declaredHeaderWriter{ResponseWriter: w, server: s, r: r, op: op}
+
 	// auth check
 	if !s.checkAuth(w, r, op) {
-		logRequest(r.Method, r.URL.Path, 401, time.Since(start))
+		logRequest(r.Method, mountedPath(r), 401, time.Since(start))
 		return
 	}
 
 	// read body for validation
 	var bodyBytes []byte
 	if r.Body != nil {
-		bodyBytes, _ = io.ReadAll(r.Body)
+		var err error
+		bodyBytes, err = readRequestBody(w, r)
+		if err == nil {
+			bodyBytes, err = decodeContentEncoding(r, bodyBytes)
+		}
+		if err != nil {
+			status := 400
+			switch {
+			case errors.Is(err, errUnsupportedEncoding):
+				status = 415
+			case errors.Is(err, errBodyTooLarge):
+				status = 413
+			}
+			s.writeError(w, r, op, mockError{status: status, detail: err.Error()})
+			logRequest(r.Method, mountedPath(r), status, time.Since(start))
+			return
+		}
 		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 	}
 
 	// request validation
 	if len(bodyBytes) > 0 {
-		if !s.validateRequest(w, r, bodyBytes) {
-			logRequestValidationError(r.Method, r.URL.Path, time.Since(start))
+		if accepted, unsupported := unsupportedRequestType(r, op); unsupported {
+			s.writeError(w, r, op, mockError{status: 415, detail: "unsupported media type — supported: " + strings.Join(accepted, ", ")})
+			logRequest(r.Method, mountedPath(r), 415, time.Since(start))
+			return
+		}
+		if !s.validateRequest(w, r, op, bodyBytes) {
+			logRequestValidationError(r.Method, mountedPath(r), time.Since(start))
 			return
 		}
 		// strict mode: additional required field checking
 		if strictMode && op.RequestBody != nil && op.RequestBody.Value != nil {
-			ct := op.RequestBody.Value.Content.Get("application/json")
-			if ct != nil && ct.Schema != nil {
-				var bodyMap map[string]interface{}
-				if json.Unmarshal(bodyBytes, &bodyMap) == nil {
-					errs := strictValidateRequestBody(ct.Schema, bodyMap)
+			if schema := requestBodySchema(op, requestMediaType(r)); schema != nil {
+				if bodyMap, _, ok := parseRequestBody(bodyBytes, r.Header.Get("Content-Type"), schema); ok {
+					errs := strictValidateRequestBody(schema, bodyMap)
 					if len(errs) > 0 {
-						w.Header().Set("Content-Type", "application/json")
-						w.WriteHeader(400)
 						details := make([]map[string]string, len(errs))
 						for i, e := range errs {
 							details[i] = map[string]string{"message": e}
 						}
-						json.NewEncoder(w).Encode(map[string]interface{}{
-							"error":   "strict validation failed",
-							"details": details,
-						})
-						logRequestValidationError(r.Method, r.URL.Path, time.Since(start))
+						s.writeError(w, r, op, mockError{status: 400, detail: "strict validation failed", details: details})
+						logRequestValidationError(r.Method, mountedPath(r), time.Since(start))
 						return
 					}
 				}
@@ -873,7 +956,30 @@ func (s *MockServer) handleRequest(w http.ResponseWriter, r *http.Request) {
 
 	// Prefer header
 	if s.handlePreferCode(w, r, op, contentType) {
-		logRequest(r.Method, r.URL.Path, parsePreferCode(r), time.Since(start))
+		logRequest(r.Method, mountedPath(r), parsePreferCode(r), time.Since(start))
+		return
+	}
+
+	// server-sent events. the stream can stay open for a long time, so it
+	// runs without the spec lock (hot reload would wait on it otherwise)
+	if contentType == "text/event-stream" && r.Method == "GET" {
+		stream := s.newEventStream(r, op)
+		s.mu.RUnlock()
+		code := stream.serve(w, r)
+		s.mu.RLock()
+		logRequest(r.Method, mountedPath(r), code, time.Since(start))
+		return
+	}
+
+	// file downloads, images, PDFs and multipart/mixed
+	if binaryType != "" && (r.Method == "GET" || r.Method == "POST") {
+		if f := s.storedUpload(r.URL.Path); f != nil && r.Method == "GET" {
+			writeStoredFile(w, f, 200)
+			logRequest(r.Method, mountedPath(r), 200, time.Since(start))
+			return
+		}
+		code := s.handleBinary(w, r, op, binaryType)
+		logRequest(r.Method, mountedPath(r), code, time.Since(start))
 		return
 	}
 
@@ -892,19 +998,19 @@ func (s *MockServer) handleRequest(w http.ResponseWriter, r *http.Request) {
 		if id, ok := params["id"]; ok {
 			s.handlePut(w, r, op, resource, id, contentType)
 		} else {
-			writeResponse(w, contentType, 400, map[string]string{"error": "missing id"})
+			s.writeError(w, r, op, mockError{status: 400, detail: "missing id"})
 		}
 	case "DELETE":
 		if id, ok := params["id"]; ok {
 			s.handleDelete(w, r, resource, id)
 		} else {
-			writeResponse(w, contentType, 400, map[string]string{"error": "missing id"})
+			s.writeError(w, r, op, mockError{status: 400, detail: "missing id"})
 		}
 	default:
 		s.handleGeneric(w, r, op, contentType)
 	}
 
-	logRequest(r.Method, r.URL.Path, 200, time.Since(start))
+	logRequest(r.Method, mountedPath(r), 200, time.Since(start))
 }
 
 func extractResource(path string) string {
@@ -916,13 +1022,7 @@ func extractResource(path string) string {
 }
 
 func (s *MockServer) handlePost(w http.ResponseWriter, r *http.Request, op *openapi3.Operation, resource, contentType string) {
-	var body map[string]interface{}
-	if r.Body != nil {
-		json.NewDecoder(r.Body).Decode(&body)
-	}
-	if body == nil {
-		body = make(map[string]interface{})
-	}
+	body := s.decodeRequestBody(r, op)
 
 	if _, ok := body["id"]; !ok {
 		body["id"] = gofakeit.UUID()
@@ -931,7 +1031,8 @@ func (s *MockServer) handlePost(w http.ResponseWriter, r *http.Request, op *open
 	id := fmt.Sprintf("%v", body["id"])
 	s.store.Put(resource, id, body)
 
-	writeResponse(w, contentType, 201, body)
+	w.Header().Set("Location", strings.TrimRight(mountedPath(r), "/")+"/"+url.PathEscape(id))
+	s.respond(w, op, contentType, 201, body)
 
 	// fire webhook
 	s.webhookMgr.FireWebhook("POST", resource, 201, body)
@@ -940,19 +1041,19 @@ func (s *MockServer) handlePost(w http.ResponseWriter, r *http.Request, op *open
 func (s *MockServer) handleGetOne(w http.ResponseWriter, r *http.Request, op *openapi3.Operation, resource, id, contentType string) {
 	obj, ok := s.store.Get(resource, id)
 	if ok {
-		writeResponse(w, contentType, 200, obj)
+		s.respond(w, op, contentType, 200, obj)
 		return
 	}
 
 	// if the resource has been written to (POST/PUT/DELETE happened), return 404 for missing items
 	if s.store.HasBeenWritten(resource) {
-		writeResponse(w, contentType, 404, map[string]string{"error": "not found"})
+		s.writeError(w, r, op, mockError{status: 404, detail: resource + " " + id + " not found"})
 		return
 	}
 
-	schema := s.getResponseSchema(op, "200")
+	schema := s.getResponseSchema(op, "200", contentType)
 	if schema == nil {
-		schema = s.getResponseSchema(op, "201")
+		schema = s.getResponseSchema(op, "201", contentType)
 	}
 	if schema != nil {
 		rng := seededRng(s.seed, r.URL.Path)
@@ -960,49 +1061,54 @@ func (s *MockServer) handleGetOne(w http.ResponseWriter, r *http.Request, op *op
 		if m, ok := fake.(map[string]interface{}); ok {
 			m["id"] = id
 		}
-		writeResponse(w, contentType, 200, fake)
+		s.respond(w, op, contentType, 200, fake)
 		return
 	}
 
-	writeResponse(w, contentType, 200, map[string]interface{}{"id": id})
+	s.respond(w, op, contentType, 200, map[string]interface{}{"id": id})
 }
 
 func (s *MockServer) handleGetList(w http.ResponseWriter, r *http.Request, op *openapi3.Operation, resource, contentType string) {
 	items := s.store.List(resource)
 	if s.store.HasBeenWritten(resource) {
 		items = applyQueryParams(items, r.URL.Query())
-		writeResponse(w, contentType, 200, items)
+		s.respond(w, op, contentType, 200, items)
 		return
 	}
 
 	if len(items) == 0 {
-		schema := s.getResponseSchema(op, "200")
+		schema := s.getResponseSchema(op, "200", contentType)
+		if schema != nil && schema.Value != nil && schema.Value.Type.Is("array") && schema.Value.Example == nil {
+			rng := seededRng(s.seed, r.URL.Path)
+			sizes := arrayCountRange(schema.Value)
+			if n, ok := parsePreferCount(r); ok {
+				sizes = clampCount(schema.Value, countRange{min: n, max: n})
+			}
+			page, total := generateListPage(schema.Value, s.seed, r.URL.Path, sizes.pick(rng), r.URL.Query())
+			w.Header().Set("X-Total-Count", strconv.Itoa(total))
+			s.respond(w, op, contentType, 200, page)
+			return
+		}
 		if schema != nil {
 			rng := seededRng(s.seed, r.URL.Path)
 			fake := generateFromSchema(schema, rng, 0)
 			// if fake data is an array, apply query params
 			if arr, ok := fake.([]interface{}); ok {
 				arr = applyQueryParams(arr, r.URL.Query())
-				writeResponse(w, contentType, 200, arr)
+				s.respond(w, op, contentType, 200, arr)
 				return
 			}
-			writeResponse(w, contentType, 200, fake)
+			s.respond(w, op, contentType, 200, fake)
 			return
 		}
 	}
 
 	items = applyQueryParams(items, r.URL.Query())
-	writeResponse(w, contentType, 200, items)
+	s.respond(w, op, contentType, 200, items)
 }
 
 func (s *MockServer) handlePut(w http.ResponseWriter, r *http.Request, op *openapi3.Operation, resource, id, contentType string) {
-	var body map[string]interface{}
-	if r.Body != nil {
-		json.NewDecoder(r.Body).Decode(&body)
-	}
-	if body == nil {
-		body = make(map[string]interface{})
-	}
+	body := s.decodeRequestBody(r, op)
 	body["id"] = id
 
 	existing, ok := s.store.Get(resource, id)
@@ -1016,7 +1122,7 @@ func (s *MockServer) handlePut(w http.ResponseWriter, r *http.Request, op *opena
 	}
 
 	s.store.Put(resource, id, body)
-	writeResponse(w, contentType, 200, body)
+	s.respond(w, op, contentType, 200, body)
 
 	// fire webhook
 	s.webhookMgr.FireWebhook("PUT", resource, 200, body)
@@ -1031,15 +1137,15 @@ func (s *MockServer) handleDelete(w http.ResponseWriter, r *http.Request, resour
 }
 
 func (s *MockServer) handleGeneric(w http.ResponseWriter, r *http.Request, op *openapi3.Operation, contentType string) {
-	schema := s.getResponseSchema(op, "200")
+	schema := s.getResponseSchema(op, "200", contentType)
 	if schema != nil {
 		rng := seededRng(s.seed, r.URL.Path)
 		fake := generateFromSchema(schema, rng, 0)
 		s.strictValidateResponse(schema, fake, r.URL.Path)
-		writeResponse(w, contentType, 200, fake)
+		s.respond(w, op, contentType, 200, fake)
 		return
 	}
-	writeResponse(w, contentType, 200, map[string]string{"status": "ok"})
+	s.respond(w, op, contentType, 200, map[string]string{"status": "ok"})
 }
 
 func (s *MockServer) strictValidateResponse(schema *openapi3.SchemaRef, data interface{}, path string) {
@@ -1049,7 +1155,9 @@ func (s *MockServer) strictValidateResponse(schema *openapi3.SchemaRef, data int
 	}
 }
 
-func (s *MockServer) getResponseSchema(op *openapi3.Operation, statusCode string) *openapi3.SchemaRef {
+// getResponseSchema returns the schema declared for mediaType, falling back to
+// JSON and then to whatever structured type the response declares
+func (s *MockServer) getResponseSchema(op *openapi3.Operation, statusCode, mediaType string) *openapi3.SchemaRef {
 	if op.Responses == nil {
 		return nil
 	}
@@ -1060,11 +1168,22 @@ func (s *MockServer) getResponseSchema(op *openapi3.Operation, statusCode string
 	if resp.Value == nil {
 		return nil
 	}
-	ct := resp.Value.Content.Get("application/json")
-	if ct == nil {
-		return nil
+	for _, mt := range []string{mediaType, "application/json"} {
+		if ct := resp.Value.Content.Get(mt); ct != nil && ct.Schema != nil {
+			return ct.Schema
+		}
 	}
-	return ct.Schema
+	types := make([]string, 0, len(resp.Value.Content))
+	for mt := range resp.Value.Content {
+		types = append(types, mt)
+	}
+	sort.Strings(types)
+	for _, mt := range types {
+		if ct := resp.Value.Content[mt]; !isBinaryResponse(resp.Value, mt) && ct.Schema != nil {
+			return ct.Schema
+		}
+	}
+	return nil
 }
 
 // --------------- Proxy Mode ---------------
@@ -1077,11 +1196,11 @@ func runProxy(cmd *cobra.Command, args []string) error {
 	cfg := loadConfig()
 	applyConfig(cfg)
 
-	loader := openapi3.NewLoader()
-	doc, err := loader.LoadFromFile(specFile)
+	doc, notes, err := loadSpec(specFile)
 	if err != nil {
 		return fmt.Errorf("failed to load spec: %w", err)
 	}
+	noteSpec("", notes)
 	if err := strictValidateSpec(doc); err != nil {
 		return err
 	}
@@ -1172,6 +1291,9 @@ func runProxy(cmd *cobra.Command, args []string) error {
 
 	addr := fmt.Sprintf(":%d", port)
 	srv := &http.Server{Addr: addr, Handler: handler}
+	if err := configureTLS(srv); err != nil {
+		return err
+	}
 
 	fmt.Println(renderProxyBanner(specFile, target, port, recordFile))
 	fmt.Print(renderReady(port))
@@ -1184,7 +1306,7 @@ func runProxy(cmd *cobra.Command, args []string) error {
 		srv.Shutdown(context.Background())
 	}()
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed { return err }; return nil
+	return serveHTTP(srv)
 }
 
 // --------------- Recorder ---------------
@@ -1273,6 +1395,9 @@ func runReplay(cmd *cobra.Command, args []string) error {
 
 	addr := fmt.Sprintf(":%d", port)
 	srv := &http.Server{Addr: addr, Handler: handler}
+	if err := configureTLS(srv); err != nil {
+		return err
+	}
 
 	fmt.Println(renderReplayBanner(recordFile, port, len(recordings)))
 	fmt.Print(renderReady(port))
@@ -1285,7 +1410,7 @@ func runReplay(cmd *cobra.Command, args []string) error {
 		srv.Shutdown(context.Background())
 	}()
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed { return err }; return nil
+	return serveHTTP(srv)
 }
 
 // --------------- Fake Data Generation ---------------
@@ -1325,10 +1450,10 @@ func generateFromSchema(ref *openapi3.SchemaRef, rng *rand.Rand, depth int) inte
 	}
 
 	if len(schema.OneOf) > 0 {
-		return generateFromSchema(schema.OneOf[0], rng, depth+1)
+		return generateFromSchema(firstNonNull(schema.OneOf), rng, depth+1)
 	}
 	if len(schema.AnyOf) > 0 {
-		return generateFromSchema(schema.AnyOf[0], rng, depth+1)
+		return generateFromSchema(firstNonNull(schema.AnyOf), rng, depth+1)
 	}
 
 	if schema.Example != nil {
@@ -1338,15 +1463,18 @@ func generateFromSchema(ref *openapi3.SchemaRef, rng *rand.Rand, depth int) inte
 	types := schema.Type.Slice()
 	if len(types) == 0 {
 		// no type specified, try to infer from properties
-		if len(schema.Properties) > 0 {
-			return generateObject(schema, rng, depth)
+		if len(schema.Properties) > 0 || schema.AdditionalProperties.Schema != nil {
+			return generateObject(schema, rng, depth, schemaName(ref))
+		}
+		if len(schema.Enum) > 0 {
+			return schema.Enum[rng.Intn(len(schema.Enum))]
 		}
 		return "unknown"
 	}
 
-	switch types[0] {
+	switch pickType(types, rng) {
 	case "object":
-		return generateObject(schema, rng, depth)
+		return generateObject(schema, rng, depth, schemaName(ref))
 	case "array":
 		return generateArray(schema, rng, depth)
 	case "string":
@@ -1362,15 +1490,129 @@ func generateFromSchema(ref *openapi3.SchemaRef, rng *rand.Rand, depth int) inte
 	}
 }
 
-func generateObject(schema *openapi3.Schema, rng *rand.Rand, depth int) interface{} {
+func generateObject(schema *openapi3.Schema, rng *rand.Rand, depth int, parent string) interface{} {
 	result := make(map[string]interface{})
-	for name, prop := range schema.Properties {
-		result[name] = generateFromSchemaWithName(prop, rng, depth+1, name)
+	record := newFakeRecord(schema, rng)
+	keyRe, keyEnum := propertyNamesRule(schema)
+	// walk properties in a stable order so the same seed always yields the same object
+	for _, name := range sortedPropertyNames(schema) {
+		// optional properties propertyNames rules out are left off
+		if !allowsPropertyName(keyRe, keyEnum, name) && !containsString(schema.Required, name) {
+			continue
+		}
+		result[name] = generateFromSchemaWithName(schema.Properties[name], rng, depth+1, name, parent, record)
 	}
+	generateAdditionalProperties(schema, result, rng, depth, parent)
 	return result
 }
 
-func generateFromSchemaWithName(ref *openapi3.SchemaRef, rng *rand.Rand, depth int, propName string) interface{} {
+func sortedPropertyNames(schema *openapi3.Schema) []string {
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// generateAdditionalProperties fills map-typed objects (additionalProperties)
+// with keyed entries, respecting propertyNames and min/maxProperties
+func generateAdditionalProperties(schema *openapi3.Schema, result map[string]interface{}, rng *rand.Rand, depth int, parent string) {
+	ap := schema.AdditionalProperties
+	if ap.Has != nil && !*ap.Has {
+		return
+	}
+
+	// only invent keys for real dictionaries or when minProperties demands it
+	isMap := ap.Schema != nil || (ap.Has != nil && *ap.Has && len(schema.Properties) == 0)
+	minTotal := int(schema.MinProps)
+	if !isMap && len(result) >= minTotal {
+		return
+	}
+
+	maxTotal := len(result) + 4
+	if schema.MaxProps != nil {
+		maxTotal = int(*schema.MaxProps)
+	}
+	target := len(result)
+	if isMap {
+		target = len(result) + 2 + rng.Intn(3)
+	}
+	if target < minTotal {
+		target = minTotal
+	}
+	if target > maxTotal {
+		target = maxTotal
+	}
+
+	faker := gofakeit.New(uint64(rng.Int63()))
+	keyPattern, keyEnum := propertyNamesConstraints(schema)
+	keyRe, _ := propertyNamesRule(schema)
+	for attempts := 0; len(result) < target && attempts < target*10; attempts++ {
+		var key string
+		switch {
+		case len(keyEnum) > 0:
+			key = fmt.Sprintf("%v", keyEnum[rng.Intn(len(keyEnum))])
+		case keyPattern != "":
+			key = faker.Regex(keyPattern)
+		default:
+			key = strings.ToLower(faker.Word())
+		}
+		if key == "" || !allowsPropertyName(keyRe, keyEnum, key) {
+			continue
+		}
+		if _, exists := result[key]; exists {
+			continue
+		}
+		if ap.Schema != nil {
+			result[key] = generateFromSchemaWithName(ap.Schema, rng, depth+1, key, parent, nil)
+		} else {
+			result[key] = faker.Word()
+		}
+	}
+}
+
+// propertyNamesConstraints reads the JSON Schema propertyNames keyword, which
+// kin-openapi keeps in the schema extensions
+func propertyNamesConstraints(schema *openapi3.Schema) (string, []interface{}) {
+	raw, ok := schema.Extensions["propertyNames"].(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+	pattern, _ := raw["pattern"].(string)
+	enum, _ := raw["enum"].([]interface{})
+	return pattern, enum
+}
+
+// propertyNamesRule is propertyNames ready for checking keys. an invalid
+// pattern is ignored
+func propertyNamesRule(schema *openapi3.Schema) (*regexp.Regexp, []interface{}) {
+	pattern, enum := propertyNamesConstraints(schema)
+	if pattern == "" {
+		return nil, enum
+	}
+	re, _ := regexp.Compile(pattern)
+	return re, enum
+}
+
+// allowsPropertyName reports whether a key satisfies propertyNames
+func allowsPropertyName(keyRe *regexp.Regexp, keyEnum []interface{}, key string) bool {
+	if keyRe != nil && !keyRe.MatchString(key) {
+		return false
+	}
+	return len(keyEnum) == 0 || inKeyEnum(keyEnum, key)
+}
+
+func inKeyEnum(enum []interface{}, key string) bool {
+	for _, e := range enum {
+		if fmt.Sprintf("%v", e) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func generateFromSchemaWithName(ref *openapi3.SchemaRef, rng *rand.Rand, depth int, propName, parent string, record *fakeRecord) interface{} {
 	if ref == nil {
 		return nil
 	}
@@ -1379,6 +1621,16 @@ func generateFromSchemaWithName(ref *openapi3.SchemaRef, rng *rand.Rand, depth i
 		return nil
 	}
 
+	// user-defined rules win over everything inferred
+	if v, ok := generateByRules(propName, parent, schema, rng); ok {
+		return v
+	}
+
+	// related fields (name/email, city/country, ...) come from one record
+	if v, ok := record.field(propName, schema, rng); ok {
+		return v
+	}
+
 	types := schema.Type.Slice()
 	if len(types) > 0 && types[0] == "string" && schema.Format == "" && len(schema.Enum) == 0 {
 		if v, ok := generateStringByName(propName, rng); ok {
@@ -1394,7 +1646,10 @@ func generateFromSchemaWithName(ref *openapi3.SchemaRef, rng *rand.Rand, depth i
 }
 
 func generateArray(schema *openapi3.Schema, rng *rand.Rand, depth int) interface{} {
-	count := 2 + rng.Intn(4)
+	if prefix, rest, ok := tupleItems(schema); ok {
+		return generateTuple(schema, prefix, rest, rng, depth)
+	}
+	count := arrayCountRange(schema).pick(rng)
 	items := make([]interface{}, count)
 	for i := range items {
 		items[i] = generateFromSchema(schema.Items, rng, depth+1)
@@ -1402,6 +1657,19 @@ func generateArray(schema *openapi3.Schema, rng *rand.Rand, depth int) interface
 	return items
 }
 
+// generateTuple fills each prefixItems position from its own schema, adding
+// items from the rest schema only as far as minItems asks
+func generateTuple(schema *openapi3.Schema, prefix []*openapi3.SchemaRef, rest *openapi3.SchemaRef, rng *rand.Rand, depth int) interface{} {
+	items := make([]interface{}, 0, len(prefix))
+	for _, ref := range prefix {
+		items = append(items, generateFromSchema(ref, rng, depth+1))
+	}
+	for rest != nil && uint64(len(items)) < schema.MinItems {
+		items = append(items, generateFromSchema(rest, rng, depth+1))
+	}
+	return items
+}
+
 func generateStringByName(propName string, rng *rand.Rand) (string, bool) {
 	faker := gofakeit.New(uint64(rng.Int63()))
 	name := strings.ToLower(propName)
@@ -1488,9 +1756,9 @@ func generateString(schema *openapi3.Schema, rng *rand.Rand) string {
 	case "email":
 		return faker.Email()
 	case "date-time":
-		return faker.Date().Format(time.RFC3339)
+		return randomPastTime(rng).Format(time.RFC3339)
 	case "date":
-		return faker.Date().Format("2006-01-02")
+		return randomPastTime(rng).Format("2006-01-02")
 	case "uri", "url":
 		return faker.URL()
 	case "uuid":
@@ -1503,6 +1771,8 @@ func generateString(schema *openapi3.Schema, rng *rand.Rand) string {
 		return faker.DomainName()
 	case "password":
 		return faker.Password(true, true, true, false, false, 12)
+	case "byte", "binary":
+		return generateBase64(schema, rng)
 	}
 
 	maxLen := 100
@@ -1562,12 +1832,12 @@ func runDiff(cmd *cobra.Command, args []string) error {
 	target, _ := cmd.Flags().GetString("target")
 	headers, _ := cmd.Flags().GetStringArray("header")
 
-	loader := openapi3.NewLoader()
-	doc, err := loader.LoadFromFile(specFile)
+	doc, notes, err := loadSpec(specFile)
 	if err != nil {
 		return fmt.Errorf("failed to load spec: %w", err)
 	}
-	if err := doc.Validate(context.Background()); err != nil {
+	noteSpec("", notes)
+	if err := doc.Validate(context.Background(), specValidationOptions()...); err != nil {
 		stdlog.Printf("warning: spec validation issues: %v", err)
 	}
 
@@ -1703,6 +1973,9 @@ func compareShape(schemaRef *openapi3.SchemaRef, actual interface{}, prefix stri
 	}
 	schema := schemaRef.Value
 	var diffs []string
+	if actual == nil && schema.Nullable {
+		return nil
+	}
 
 	types := schema.Type.Slice()
 	if len(types) == 0 && len(schema.Properties) > 0 {
@@ -1711,8 +1984,12 @@ func compareShape(schemaRef *openapi3.SchemaRef, actual interface{}, prefix stri
 	if len(types) == 0 {
 		return nil
 	}
+	typ := matchType(types, actual)
+	if typ == "" {
+		return []string{fmt.Sprintf("❌ %s: expected %s, got %T", fieldPath(prefix, ""), describeTypes(types), actual)}
+	}
 
-	switch types[0] {
+	switch typ {
 	case "object":
 		m, ok := actual.(map[string]interface{})
 		if !ok {
@@ -1739,7 +2016,11 @@ func compareShape(schemaRef *openapi3.SchemaRef, actual interface{}, prefix stri
 		if !ok {
 			return []string{fmt.Sprintf("❌ %s: expected array, got %T", fieldPath(prefix, ""), actual)}
 		}
-		if len(arr) > 0 && schema.Items != nil {
+		if tuple, _, ok := tupleItems(schema); ok {
+			for i := 0; i < len(tuple) && i < len(arr); i++ {
+				diffs = append(diffs, compareShape(tuple[i], arr[i], fmt.Sprintf("%s[%d]", prefix, i))...)
+			}
+		} else if len(arr) > 0 && schema.Items != nil {
 			diffs = append(diffs, compareShape(schema.Items, arr[0], prefix+"[0]")...)
 		}
 	case "string":
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	stdlog "log"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// SpecConfig is one entry of the specs list in .portblock.yaml, for serving
+// several specs from one process
+type SpecConfig struct {
+	Spec   string `yaml:"spec" json:"spec"`
+	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	Port   int    `yaml:"port,omitempty" json:"port,omitempty"`
+}
+
+// specMount is a spec as serve runs it
+type specMount struct {
+	name   string
+	file   string
+	prefix string
+	port   int
+	server *MockServer
+	source *specSource
+}
+
+// parseSpecMounts reads serve's arguments, each a spec file optionally
+// preceded by where to mount it: /prefix=file, :port=file or :port/prefix=file.
+// without arguments the config's specs list is used. several specs without a
+// mount each get a prefix named after their file
+func parseSpecMounts(args []string, cfg *Config) ([]*specMount, error) {
+	var mounts []*specMount
+	for _, arg := range args {
+		m := &specMount{file: arg, port: port}
+		if at, file, ok := strings.Cut(arg, "="); ok && (strings.HasPrefix(at, "/") || strings.HasPrefix(at, ":")) {
+			m.file = file
+			if rest, ok := strings.CutPrefix(at, ":"); ok {
+				portStr, prefix, _ := strings.Cut(rest, "/")
+				p, err := strconv.Atoi(portStr)
+				if err != nil || p <= 0 || p > 65535 {
+					return nil, fmt.Errorf("invalid port in %q", arg)
+				}
+				m.port = p
+				if prefix != "" {
+					m.prefix = "/" + prefix
+				}
+			} else {
+				m.prefix = at
+			}
+		}
+		mounts = append(mounts, m)
+	}
+	if len(mounts) == 0 && cfg != nil {
+		for _, sc := range cfg.Specs {
+			file := sc.Spec
+			if !filepath.IsAbs(file) && !isSpecURL(file) && cfg.path != "" {
+				file = filepath.Join(filepath.Dir(cfg.path), file)
+			}
+			m := &specMount{file: file, prefix: sc.Prefix, port: port}
+			if sc.Port != 0 {
+				m.port = sc.Port
+			}
+			mounts = append(mounts, m)
+		}
+	}
+	if len(mounts) == 0 {
+		return nil, fmt.Errorf("serve needs a spec file, or a specs list in .portblock.yaml")
+	}
+
+	names := map[string]bool{}
+	at := map[string]string{}
+	for _, m := range mounts {
+		m.name = specName(m.file)
+		for base, i := m.name, 2; names[m.name]; i++ {
+			m.name = fmt.Sprintf("%s-%d", base, i)
+		}
+		names[m.name] = true
+		if len(mounts) > 1 && m.prefix == "" && m.port == port {
+			m.prefix = "/" + m.name
+		}
+		m.prefix = cleanBasePath(m.prefix)
+		key := fmt.Sprintf(":%d%s", m.port, m.prefix)
+		if other, ok := at[key]; ok {
+			return nil, fmt.Errorf("%s and %s are both mounted at %s", other, m.file, key)
+		}
+		at[key] = m.file
+	}
+	return mounts, nil
+}
+
+// loadMockServer loads a spec and sets up a mock server with its own store.
+// name labels the spec's conversion notes when serving several
+func loadMockServer(m *specMount, name string, async *AsyncAPI) (*MockServer, error) {
+	doc := &openapi3.T{OpenAPI: "3.0.3", Info: &openapi3.Info{Title: "asyncapi", Version: "0"}, Paths: openapi3.NewPaths()}
+	if async == nil || m.file != asyncAPIFile {
+		var notes []string
+		var err error
+		doc, notes, m.source, err = loadSpecSource(m.file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load spec %s: %w", m.file, err)
+		}
+		noteSpec(name, notes)
+	}
+	if err := strictValidateSpec(doc); err != nil {
+		return nil, err
+	}
+
+	router, err := newValidationRouter(doc)
+	if err != nil {
+		stdlog.Printf("warning: could not build validation router for %s: %v", m.file, err)
+	}
+
+	return &MockServer{
+		doc:        doc,
+		store:      NewStore(),
+		seed:       seed,
+		router:     router,
+		routes:     newRouteTable(doc),
+		prefix:     m.prefix,
+		basePaths:  basePaths(doc),
+		noAuth:     noAuth,
+		webhookMgr: NewWebhookManager(webhookTarget, webhookDelay, doc),
+		propOrder:  buildPropertyOrder(doc, m.source),
+		async:      async,
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// remotePollInterval is how often specs loaded over HTTP are checked for changes
+var remotePollInterval = 2 * time.Second
+
+// watch hot reloads the spec when it or any document its refs pull in
+// changes. directories are watched rather than files, so editors that save by
+// renaming a new file over the old one are caught too; documents loaded over
+// HTTP are polled. the returned func stops watching
+func (s *MockServer) watch(specFile string, src *specSource) func() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		stdlog.Printf("warning: could not start file watcher: %v", err)
+		return func() {}
+	}
+	var files map[string]bool
+	var remote map[string][]byte
+	track := func(src *specSource) {
+		files, remote = map[string]bool{}, map[string][]byte{}
+		if src == nil {
+			return
+		}
+		dirs := map[string]bool{}
+		for _, name := range watchedRulesFiles() {
+			if name != "" {
+				files[name] = true
+				dirs[filepath.Dir(name)] = true
+			}
+		}
+		for name, data := range src.files {
+			if isSpecURL(name) {
+				remote[name] = data
+				continue
+			}
+			files[name] = true
+			dirs[filepath.Dir(name)] = true
+		}
+		for _, dir := range watcher.WatchList() {
+			if !dirs[dir] {
+				watcher.Remove(dir)
+			}
+		}
+		for dir := range dirs {
+			if err := watcher.Add(dir); err != nil {
+				stdlog.Printf("warning: could not watch %s: %v", dir, err)
+			}
+		}
+	}
+	track(src)
+
+	go func() {
+		poll := time.NewTicker(remotePollInterval)
+		defer poll.Stop()
+		var debounce <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if files[filepath.Clean(event.Name)] && !event.Has(fsnotify.Chmod) {
+					debounce = time.After(200 * time.Millisecond)
+				}
+			case <-poll.C:
+				if remoteChanged(remote) {
+					debounce = time.After(0)
+				}
+			case <-debounce:
+				if err := reloadFieldRules(); err != nil {
+					logReloadError(err)
+				}
+				newDoc, _, newSrc, err := loadSpecSource(specFile)
+				if err != nil {
+					logReloadError(err)
+					continue
+				}
+				if err := newDoc.Validate(context.Background(), specValidationOptions()...); err != nil {
+					logReloadError(err)
+					continue
+				}
+				newRouter, err := newValidationRouter(newDoc)
+				if err != nil {
+					logReloadError(err)
+					continue
+				}
+				track(newSrc)
+				s.mu.Lock()
+				s.doc = newDoc
+				s.router = newRouter
+				s.routes = newRouteTable(newDoc)
+				s.basePaths = basePaths(newDoc)
+				s.propOrder = buildPropertyOrder(newDoc, newSrc)
+				s.webhookMgr = NewWebhookManager(webhookTarget, webhookDelay, newDoc)
+				s.mu.Unlock()
+				logReload(specFile)
+				printRoutes(newDoc, s.prefix)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				stdlog.Printf("watcher error: %v", err)
+			}
+		}
+	}()
+	return func() { watcher.Close() }
+}
+
+// remoteChanged fetches each remote document again. one that can't be
+// fetched right now doesn't count as a change
+func remoteChanged(remote map[string][]byte) bool {
+	for name, data := range remote {
+		location, err := url.Parse(name)
+		if err != nil {
+			continue
+		}
+		if latest, err := readSpecURI(location); err == nil && !bytes.Equal(latest, data) {
+			return true
+		}
+	}
+	return false
+}
+
+// specMux sends each request on a port to the spec mounted at the longest
+// matching prefix. /__portblock/ is shared by all specs on every port
+type specMux struct {
+	mounts []*specMount // longest prefix first
+	all    []*specMount
+}
+
+func newSpecMux(mounts, all []*specMount) *specMux {
+	sorted := append([]*specMount(nil), mounts...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].prefix) > len(sorted[j].prefix) })
+	return &specMux{mounts: sorted, all: all}
+}
+
+func (m *specMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/__portblock/") {
+		m.serveAdmin(w, r)
+		return
+	}
+	for _, mount := range m.mounts {
+		if _, ok := cutBasePath(r.URL.Path, mount.prefix); ok || mount.prefix == "" {
+			mount.server.handleRequest(w, r)
+			return
+		}
+	}
+	start := time.Now()
+	prefixes := make([]string, len(m.mounts))
+	for i, mount := range m.mounts {
+		prefixes[i] = mount.prefix
+	}
+	m.mounts[0].server.writeError(w, r, nil, mockError{status: 404, detail: "no spec mounted here — try " + strings.Join(prefixes, ", ")})
+	logRequest(r.Method, r.URL.Path, 404, time.Since(start))
+}
+
+// serveAdmin handles portblock's own endpoints:
+//
+//	GET  /__portblock/specs         the mounted specs
+//	POST /__portblock/reset[?spec=] clear every store, or one spec's
+//	GET  /__portblock/files/...     uploaded files, from whichever store has them
+func (m *specMux) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := 200
+	switch {
+	case strings.HasPrefix(r.URL.Path, filesPrefix):
+		id, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, filesPrefix), "/")
+		for _, mount := range m.all {
+			if _, ok := mount.server.store.GetFile(id); ok {
+				mount.server.handleRequest(w, r)
+				return
+			}
+		}
+		m.mounts[0].server.handleRequest(w, r)
+		return
+	case r.URL.Path == "/__portblock/specs" && r.Method == "GET":
+		specs := make([]map[string]interface{}, 0, len(m.all))
+		for _, mount := range m.all {
+			mount.server.mu.RLock()
+			paths := len(mount.server.doc.Paths.Map())
+			mount.server.mu.RUnlock()
+			specs = append(specs, map[string]interface{}{
+				"name":  mount.name,
+				"spec":  mount.file,
+				"url":   mount.url(),
+				"paths": paths,
+			})
+		}
+		writeResponse(w, "application/json", 200, specs)
+	case r.URL.Path == "/__portblock/reset" && r.Method == "POST":
+		name := r.URL.Query().Get("spec")
+		reset := []string{}
+		for _, mount := range m.all {
+			if name == "" || name == mount.name {
+				mount.server.store.Reset()
+				reset = append(reset, mount.name)
+			}
+		}
+		if len(reset) == 0 {
+			status = 404
+			m.mounts[0].server.writeError(w, r, nil, mockError{status: 404, detail: "no spec named " + name})
+			break
+		}
+		writeResponse(w, "application/json", 200, map[string]interface{}{"reset": reset})
+	default:
+		status = 404
+		m.mounts[0].server.writeError(w, r, nil, mockError{status: 404, detail: "unknown portblock endpoint"})
+	}
+	logRequest(r.Method, r.URL.Path, status, time.Since(start))
+}
+
+// specName names a spec after its file, without the extension
+func specName(file string) string {
+	if isSpecURL(file) {
+		if u, err := url.Parse(file); err == nil {
+			if file = path.Base(u.Path); file == "/" || file == "." {
+				return u.Hostname()
+			}
+		}
+	}
+	file = filepath.Base(file)
+	return strings.TrimSuffix(file, filepath.Ext(file))
+}
+
+func (m *specMount) url() string {
+	return fmt.Sprintf("%s://localhost:%d%s", scheme(), m.port, m.prefix)
+}
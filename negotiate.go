@@ -0,0 +1,180 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// defaultMediaTypes are offered for routes that don't declare response content
+var defaultMediaTypes = []string{"application/json", "application/xml", "text/xml"}
+
+// acceptRange is one entry of an Accept header
+type acceptRange struct {
+	mediaType string
+	q         float64
+	index     int
+}
+
+// parseAccept parses an Accept header; a missing header accepts anything
+func parseAccept(header string) []acceptRange {
+	if strings.TrimSpace(header) == "" {
+		return []acceptRange{{mediaType: "*/*", q: 1}}
+	}
+	var ranges []acceptRange
+	for i, part := range strings.Split(header, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			// mime rejects a bare "*", which some clients send
+			if strings.TrimSpace(part) != "*" {
+				continue
+			}
+			mt = "*/*"
+		}
+		if mt == "*" {
+			mt = "*/*"
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+				q = f
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mt, q: q, index: i})
+	}
+	return ranges
+}
+
+func specificity(mt string) int {
+	switch {
+	case mt == "*/*":
+		return 0
+	case strings.HasSuffix(mt, "/*"):
+		return 1
+	}
+	return 2
+}
+
+// quality finds the most specific Accept entry matching a declared media type
+func quality(ranges []acceptRange, declared string) (acceptRange, bool) {
+	var best acceptRange
+	found := false
+	for _, ar := range ranges {
+		if !mediaTypeMatches(ar.mediaType, declared) {
+			continue
+		}
+		if !found || specificity(ar.mediaType) > specificity(best.mediaType) {
+			best, found = ar, true
+		}
+	}
+	return best, found
+}
+
+// negotiateMediaType picks the declared media type the client prefers: highest
+// q-value first, then the earlier Accept entry, then the order of declared
+// (JSON before XML before text before files). returns "" when nothing is acceptable
+func negotiateMediaType(r *http.Request, declared []string) string {
+	ranges := parseAccept(r.Header.Get("Accept"))
+	chosen := ""
+	var chosenRange acceptRange
+	for _, mt := range declared {
+		ar, ok := quality(ranges, mt)
+		if !ok || ar.q <= 0 {
+			continue
+		}
+		if chosen == "" || ar.q > chosenRange.q || (ar.q == chosenRange.q && ar.index < chosenRange.index) {
+			chosen, chosenRange = mt, ar
+		}
+	}
+	return chosen
+}
+
+// responseMediaTypes lists the media types an operation's success response
+// declares, in the order ties are broken
+func responseMediaTypes(op *openapi3.Operation) []string {
+	_, resp := successResponse(op)
+	if resp == nil || len(resp.Content) == 0 {
+		return defaultMediaTypes
+	}
+	types := make([]string, 0, len(resp.Content))
+	for mt := range resp.Content {
+		types = append(types, strings.ToLower(mt))
+	}
+	sort.Slice(types, func(i, j int) bool {
+		ri, rj := mediaRank(types[i], resp), mediaRank(types[j], resp)
+		if ri != rj {
+			return ri < rj
+		}
+		return types[i] < types[j]
+	})
+	return types
+}
+
+func mediaRank(mt string, resp *openapi3.Response) int {
+	switch {
+	case isBinaryResponse(resp, mt):
+		return 3
+	case isJSONMediaType(mt):
+		return 0
+	case isXMLMediaType(mt):
+		return 1
+	}
+	return 2
+}
+
+// isBinaryResponse reports whether a declared media type is served as a file
+func isBinaryResponse(resp *openapi3.Response, mt string) bool {
+	if isBinaryMediaType(mt) {
+		return true
+	}
+	if resp == nil {
+		return false
+	}
+	media := resp.Content.Get(mt)
+	return media != nil && media.Schema != nil && media.Schema.Value != nil && media.Schema.Value.Format == "binary"
+}
+
+func isJSONMediaType(mt string) bool {
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+func isXMLMediaType(mt string) bool {
+	return mt == "application/xml" || mt == "text/xml" || (strings.HasSuffix(mt, "+xml") && !isBinaryMediaType(mt))
+}
+
+// mediaFormat is how data is serialized for a content type. anything not XML
+// or text is JSON, which covers vendor types like application/vnd.api+json
+func mediaFormat(contentType string) string {
+	mt, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	mt = strings.TrimSpace(mt)
+	switch {
+	case isXMLMediaType(mt):
+		return "xml"
+	case mt == "text/csv":
+		return "csv"
+	case strings.HasPrefix(mt, "text/"):
+		return "text"
+	}
+	return "json"
+}
+
+// unsupportedRequestType reports whether a request body's content type isn't
+// one the operation accepts, returning the accepted types
+func unsupportedRequestType(r *http.Request, op *openapi3.Operation) ([]string, bool) {
+	if op.RequestBody == nil || op.RequestBody.Value == nil || len(op.RequestBody.Value.Content) == 0 {
+		return nil, false
+	}
+	if op.RequestBody.Value.Content.Get(requestMediaType(r)) != nil {
+		return nil, false
+	}
+	accepted := make([]string, 0, len(op.RequestBody.Value.Content))
+	for mt := range op.RequestBody.Value.Content {
+		accepted = append(accepted, mt)
+	}
+	sort.Strings(accepted)
+	return accepted, true
+}
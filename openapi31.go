@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// kin-openapi speaks OpenAPI 3.0, so 3.1 documents are rewritten into their
+// 3.0 equivalent as they're read: type arrays with "null" become nullable,
+// const an enum of one, schema examples the first example, and numeric
+// exclusiveMinimum/Maximum the 3.0 booleans. prefixItems become an anyOf on
+// items, marked so generation and validation can treat them positionally.
+// webhooks are kept as a components callback so their refs get resolved
+
+// webhooksCallback is the components callback 3.1 webhooks are kept under
+const webhooksCallback = "portblock.webhooks"
+
+// prefixItemsKey marks how many of an array's items.anyOf are tuple positions;
+// any entry after them is the schema for the rest
+const prefixItemsKey = "x-portblock-prefix-items"
+
+// unsupported31 are JSON Schema keywords the mock doesn't act on
+var unsupported31 = []string{"if", "then", "else", "dependentSchemas", "unevaluatedProperties", "unevaluatedItems", "contains", "$dynamicRef"}
+
+// keywords31 are 3.1 keywords left on schemas after normalizing
+var keywords31 = append([]string{"$defs", "$schema", "$id", "$anchor", "$comment", "contentMediaType", "contentEncoding", "dependentRequired", "minContains", "maxContains"}, unsupported31...)
+
+// namedMaps are objects whose keys are names rather than keywords, so a
+// property called const or type is left alone
+var namedMaps = map[string]bool{
+	"properties": true, "patternProperties": true, "$defs": true, "definitions": true, "dependentSchemas": true,
+	"schemas": true, "responses": true, "parameters": true, "requestBodies": true, "headers": true,
+	"securitySchemes": true, "links": true, "callbacks": true, "pathItems": true, "content": true,
+	"encoding": true, "variables": true, "paths": true, "webhooks": true, "mapping": true,
+}
+
+// dataKeys hold instance values, not schemas
+var dataKeys = map[string]bool{"example": true, "examples": true, "enum": true, "const": true, "default": true, "value": true}
+
+// isOpenAPI31 reports whether a document declares openapi 3.1
+func isOpenAPI31(data []byte) bool {
+	var head struct {
+		OpenAPI string `yaml:"openapi"`
+	}
+	return yaml.Unmarshal(data, &head) == nil && strings.HasPrefix(head.OpenAPI, "3.1")
+}
+
+// notes31 says a spec was converted and which keywords were ignored
+func notes31(found map[string]bool) []string {
+	notes := []string{"converted from openapi 3.1"}
+	if len(found) > 0 {
+		keys := make([]string, 0, len(found))
+		for k := range found {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		notes = append(notes, "ignored keywords: "+strings.Join(keys, ", "))
+	}
+	return notes
+}
+
+// normalize31 rewrites one 3.1 file as 3.0 JSON. found collects the
+// unsupported keywords it comes across
+func normalize31(data []byte, found map[string]bool) ([]byte, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	raw = jsonValue(raw)
+	if root, ok := raw.(map[string]interface{}); ok {
+		if _, ok := root["openapi"]; ok {
+			normalizeRoot(root)
+		}
+	}
+	walk31(raw, false, found)
+	return json.Marshal(raw)
+}
+
+// normalizeRoot downgrades the version, makes paths optional like 3.1 does
+// and moves webhooks where kin-openapi resolves them
+func normalizeRoot(root map[string]interface{}) {
+	root["openapi"] = "3.0.3"
+	delete(root, "jsonSchemaDialect")
+	if _, ok := root["paths"]; !ok {
+		root["paths"] = map[string]interface{}{}
+	}
+	if info, ok := root["info"].(map[string]interface{}); ok {
+		delete(info, "summary")
+		if license, ok := info["license"].(map[string]interface{}); ok {
+			if id, ok := license["identifier"].(string); ok && license["url"] == nil {
+				license["url"] = "https://spdx.org/licenses/" + id + ".html"
+			}
+			delete(license, "identifier")
+		}
+	}
+	webhooks, ok := root["webhooks"].(map[string]interface{})
+	delete(root, "webhooks")
+	if !ok || len(webhooks) == 0 {
+		return
+	}
+	components, _ := root["components"].(map[string]interface{})
+	if components == nil {
+		components = map[string]interface{}{}
+		root["components"] = components
+	}
+	callbacks, _ := components["callbacks"].(map[string]interface{})
+	if callbacks == nil {
+		callbacks = map[string]interface{}{}
+		components["callbacks"] = callbacks
+	}
+	callbacks[webhooksCallback] = webhooks
+}
+
+// walk31 normalizes every schema below v. named says v's keys are names
+func walk31(v interface{}, named bool, found map[string]bool) {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			walk31(item, false, found)
+		}
+	case map[string]interface{}:
+		if !named {
+			normalizeSchema31(v, found)
+		}
+		for k, item := range v {
+			if named {
+				walk31(item, false, found)
+			} else if !dataKeys[k] {
+				walk31(item, namedMaps[k], found)
+			}
+		}
+	}
+}
+
+func normalizeSchema31(s map[string]interface{}, found map[string]bool) {
+	// 3.1 lets $ref have siblings, 3.0 ignores them
+	if _, ok := s["$ref"]; ok {
+		for k := range s {
+			if k != "$ref" {
+				delete(s, k)
+			}
+		}
+		return
+	}
+	switch t := s["type"].(type) {
+	case []interface{}:
+		var types []interface{}
+		for _, typ := range t {
+			if typ == "null" {
+				s["nullable"] = true
+			} else {
+				types = append(types, typ)
+			}
+		}
+		switch len(types) {
+		case 0:
+			nullOnly(s)
+		case 1:
+			s["type"] = types[0]
+		default:
+			s["type"] = types
+		}
+	case string:
+		if t == "null" {
+			nullOnly(s)
+		}
+	}
+
+	if c, ok := s["const"]; ok {
+		if _, ok := s["enum"]; !ok {
+			s["enum"] = []interface{}{c}
+		}
+		delete(s, "const")
+	}
+	if examples, ok := s["examples"].([]interface{}); ok {
+		if _, ok := s["example"]; !ok && len(examples) > 0 {
+			s["example"] = examples[0]
+		}
+		delete(s, "examples")
+	}
+	// a numeric exclusive bound only replaces the inclusive one when it's tighter
+	for _, kw := range []string{"Minimum", "Maximum"} {
+		exclusive, bound := "exclusive"+kw, strings.ToLower(kw)
+		n, ok := toFloat64(s[exclusive])
+		if !ok {
+			continue
+		}
+		inclusive, hasInclusive := toFloat64(s[bound])
+		if !hasInclusive || (kw == "Minimum" && n >= inclusive) || (kw == "Maximum" && n <= inclusive) {
+			s[bound] = n
+			s[exclusive] = true
+		} else {
+			delete(s, exclusive)
+		}
+	}
+
+	if prefix, ok := s["prefixItems"].([]interface{}); ok {
+		anyOf := append([]interface{}{}, prefix...)
+		switch items := s["items"].(type) {
+		case map[string]interface{}:
+			anyOf = append(anyOf, items)
+		case bool:
+			if !items {
+				if _, ok := s["maxItems"]; !ok {
+					s["maxItems"] = len(prefix)
+				}
+			}
+		}
+		s["items"] = map[string]interface{}{"anyOf": anyOf}
+		s[prefixItemsKey] = len(prefix)
+		delete(s, "prefixItems")
+	}
+
+	for _, kw := range unsupported31 {
+		if _, ok := s[kw]; ok {
+			found[kw] = true
+		}
+	}
+}
+
+// nullOnly turns type: "null" into a schema whose only value is null
+func nullOnly(s map[string]interface{}) {
+	delete(s, "type")
+	s["nullable"] = true
+	s["enum"] = []interface{}{nil}
+}
+
+// isNullOnly reports whether a schema allows nothing but null, like the
+// {type: "null"} branch of a 3.1 anyOf
+func isNullOnly(ref *openapi3.SchemaRef) bool {
+	return ref != nil && ref.Value != nil && len(ref.Value.Enum) == 1 && ref.Value.Enum[0] == nil
+}
+
+// firstNonNull is the schema generation uses for a oneOf/anyOf
+func firstNonNull(refs openapi3.SchemaRefs) *openapi3.SchemaRef {
+	for _, ref := range refs {
+		if !isNullOnly(ref) {
+			return ref
+		}
+	}
+	return refs[0]
+}
+
+// tupleItems splits a normalized prefixItems array into its positional
+// schemas and the schema for any items after them, nil if there's none
+func tupleItems(schema *openapi3.Schema) ([]*openapi3.SchemaRef, *openapi3.SchemaRef, bool) {
+	f, ok := schema.Extensions[prefixItemsKey].(float64)
+	n := int(f)
+	if !ok || schema.Items == nil || schema.Items.Value == nil || n > len(schema.Items.Value.AnyOf) {
+		return nil, nil, false
+	}
+	anyOf := schema.Items.Value.AnyOf
+	var rest *openapi3.SchemaRef
+	if len(anyOf) > n {
+		rest = anyOf[n]
+	}
+	return anyOf[:n], rest, true
+}
+
+// pickType is the type to generate for a schema listing several
+func pickType(types []string, rng *rand.Rand) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return types[rng.Intn(len(types))]
+}
+
+// matchType is the listed type a value has, so a value is checked against
+// the branch of a multi-type schema it belongs to. "" when none fits
+func matchType(types []string, data interface{}) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	for _, t := range types {
+		if hasType(data, t) {
+			return t
+		}
+	}
+	return ""
+}
+
+func hasType(data interface{}, t string) bool {
+	switch data.(type) {
+	case map[string]interface{}:
+		return t == "object"
+	case []interface{}:
+		return t == "array"
+	case string:
+		return t == "string"
+	case bool:
+		return t == "boolean"
+	}
+	n, ok := toFloat64(data)
+	if !ok {
+		return false
+	}
+	return t == "number" || (t == "integer" && n == float64(int64(n)))
+}
+
+// specWebhooks are a 3.1 document's webhooks by name
+func specWebhooks(doc *openapi3.T) map[string]*openapi3.PathItem {
+	if doc == nil || doc.Components == nil {
+		return nil
+	}
+	cb := doc.Components.Callbacks[webhooksCallback]
+	if cb == nil || cb.Value == nil {
+		return nil
+	}
+	return cb.Value.Map()
+}
+
+// describeTypes formats a multi-type schema for messages
+func describeTypes(types []string) string {
+	return fmt.Sprintf("one of %s", strings.Join(types, ", "))
+}
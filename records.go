@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// place is a real location, so city/state/zip/country/lat/long agree with each other
+type place struct {
+	city, state, stateCode string
+	zip                    string // gofakeit pattern: # = digit, ? = letter
+	country, countryCode   string
+	callingCode            string
+	lat, lng               float64
+}
+
+var places = []place{
+	{"Boston", "Massachusetts", "MA", "021##", "United States", "US", "+1", 42.3601, -71.0589},
+	{"New York", "New York", "NY", "100##", "United States", "US", "+1", 40.7128, -74.0060},
+	{"San Francisco", "California", "CA", "941##", "United States", "US", "+1", 37.7749, -122.4194},
+	{"Los Angeles", "California", "CA", "900##", "United States", "US", "+1", 34.0522, -118.2437},
+	{"Chicago", "Illinois", "IL", "606##", "United States", "US", "+1", 41.8781, -87.6298},
+	{"Seattle", "Washington", "WA", "981##", "United States", "US", "+1", 47.6062, -122.3321},
+	{"Austin", "Texas", "TX", "787##", "United States", "US", "+1", 30.2672, -97.7431},
+	{"Denver", "Colorado", "CO", "802##", "United States", "US", "+1", 39.7392, -104.9903},
+	{"Miami", "Florida", "FL", "331##", "United States", "US", "+1", 25.7617, -80.1918},
+	{"Atlanta", "Georgia", "GA", "303##", "United States", "US", "+1", 33.7490, -84.3880},
+	{"Portland", "Oregon", "OR", "972##", "United States", "US", "+1", 45.5152, -122.6784},
+	{"Toronto", "Ontario", "ON", "M5V #?#", "Canada", "CA", "+1", 43.6532, -79.3832},
+	{"Vancouver", "British Columbia", "BC", "V6B #?#", "Canada", "CA", "+1", 49.2827, -123.1207},
+	{"London", "England", "ENG", "EC1A #??", "United Kingdom", "GB", "+44", 51.5074, -0.1278},
+	{"Manchester", "England", "ENG", "M1 #??", "United Kingdom", "GB", "+44", 53.4808, -2.2426},
+	{"Berlin", "Berlin", "BE", "10###", "Germany", "DE", "+49", 52.5200, 13.4050},
+	{"Munich", "Bavaria", "BY", "80###", "Germany", "DE", "+49", 48.1351, 11.5820},
+	{"Paris", "Île-de-France", "IDF", "750##", "France", "FR", "+33", 48.8566, 2.3522},
+	{"Amsterdam", "North Holland", "NH", "10## ??", "Netherlands", "NL", "+31", 52.3676, 4.9041},
+	{"Madrid", "Community of Madrid", "MD", "280##", "Spain", "ES", "+34", 40.4168, -3.7038},
+	{"Sydney", "New South Wales", "NSW", "2###", "Australia", "AU", "+61", -33.8688, 151.2093},
+	{"Melbourne", "Victoria", "VIC", "3###", "Australia", "AU", "+61", -37.8136, 144.9631},
+	{"Tokyo", "Tokyo", "13", "1##-####", "Japan", "JP", "+81", 35.6762, 139.6503},
+	{"São Paulo", "São Paulo", "SP", "01###-###", "Brazil", "BR", "+55", -23.5505, -46.6333},
+}
+
+var freeMailDomains = []string{"gmail.com", "outlook.com", "yahoo.com", "proton.me", "fastmail.com"}
+
+var nonAlnumRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// fakeRecord is one fake person at one address working for one company.
+// every related field of a generated object is derived from the same record
+type fakeRecord struct {
+	firstName, lastName string
+	username, email     string
+	phone               string
+	company, domain     string
+	street, zip         string
+	place               place
+	lat, lng            float64
+	times               timeline
+}
+
+// newFakeRecord draws a record up front. work emails are used when the
+// object also has a company field
+func newFakeRecord(schema *openapi3.Schema, rng *rand.Rand) *fakeRecord {
+	faker := gofakeit.New(uint64(rng.Int63()))
+	r := &fakeRecord{
+		firstName: faker.FirstName(),
+		lastName:  faker.LastName(),
+		company:   faker.Company(),
+		street:    faker.Street(),
+		place:     places[rng.Intn(len(places))],
+		times:     newTimeline(rng),
+	}
+
+	r.domain = slugify(r.company, "") + ".com"
+	r.zip, _ = faker.Generate(r.place.zip)
+	r.zip = strings.ToUpper(r.zip)
+	r.lat = r.place.lat + (rng.Float64()-0.5)/10
+	r.lng = r.place.lng + (rng.Float64()-0.5)/10
+	r.phone = r.place.callingCode + " " + faker.Numerify("### ### ####")
+
+	first := slugify(r.firstName, "")
+	last := slugify(r.lastName, "")
+	if first == "" || last == "" {
+		first, last = "user", fmt.Sprintf("%d", rng.Intn(10000))
+	}
+	switch rng.Intn(3) {
+	case 0:
+		r.username = first + "." + last
+	case 1:
+		r.username = first + last[:1]
+	default:
+		r.username = first + fmt.Sprintf("%d", 10+rng.Intn(90))
+	}
+
+	mailDomain := freeMailDomains[rng.Intn(len(freeMailDomains))]
+	for name := range schema.Properties {
+		if recordFieldKind(name) == "company" {
+			mailDomain = r.domain
+			break
+		}
+	}
+	r.email = first + "." + last + "@" + mailDomain
+
+	return r
+}
+
+// recordFieldKind maps a property name to the record attribute it represents
+func recordFieldKind(propName string) string {
+	switch strings.ToLower(propName) {
+	case "name", "full_name", "fullname", "display_name":
+		return "name"
+	case "first_name", "firstname", "given_name":
+		return "first_name"
+	case "last_name", "lastname", "surname", "family_name":
+		return "last_name"
+	case "username", "user_name", "handle", "login":
+		return "username"
+	case "email", "email_address":
+		return "email"
+	case "phone", "phone_number", "mobile", "tel":
+		return "phone"
+	case "address", "street", "street_address":
+		return "street"
+	case "city":
+		return "city"
+	case "state", "province", "region":
+		return "state"
+	case "state_code":
+		return "state_code"
+	case "zip", "zip_code", "postal_code", "zipcode":
+		return "zip"
+	case "country":
+		return "country"
+	case "country_code":
+		return "country_code"
+	case "latitude", "lat":
+		return "lat"
+	case "longitude", "lng", "lon":
+		return "lng"
+	case "company", "company_name", "organization", "org":
+		return "company"
+	case "domain", "hostname":
+		return "domain"
+	case "website", "homepage":
+		return "website"
+	}
+	return ""
+}
+
+// field returns the record's value for a property, typed for its schema
+func (r *fakeRecord) field(propName string, schema *openapi3.Schema, rng *rand.Rand) (interface{}, bool) {
+	if r == nil || schema == nil || len(schema.Enum) > 0 {
+		return nil, false
+	}
+	types := schema.Type.Slice()
+	if len(types) == 0 {
+		return nil, false
+	}
+
+	if isTemporalField(propName, schema, types[0]) {
+		return r.times.value(propName, schema, types[0], rng), true
+	}
+
+	switch kind := recordFieldKind(propName); kind {
+	case "lat", "lng":
+		v := r.lat
+		if kind == "lng" {
+			v = r.lng
+		}
+		switch types[0] {
+		case "number":
+			return v, true
+		case "string":
+			return fmt.Sprintf("%.6f", v), true
+		}
+		return nil, false
+	case "":
+		return nil, false
+	default:
+		if types[0] != "string" || (schema.Format != "" && schema.Format != "email") {
+			return nil, false
+		}
+		return r.stringField(kind), true
+	}
+}
+
+func (r *fakeRecord) stringField(kind string) string {
+	switch kind {
+	case "name":
+		return r.firstName + " " + r.lastName
+	case "first_name":
+		return r.firstName
+	case "last_name":
+		return r.lastName
+	case "username":
+		return r.username
+	case "email":
+		return r.email
+	case "phone":
+		return r.phone
+	case "street":
+		return r.street
+	case "city":
+		return r.place.city
+	case "state":
+		return r.place.state
+	case "state_code":
+		return r.place.stateCode
+	case "zip":
+		return r.zip
+	case "country":
+		return r.place.country
+	case "country_code":
+		return r.place.countryCode
+	case "company":
+		return r.company
+	case "domain":
+		return r.domain
+	case "website":
+		return "https://www." + r.domain
+	}
+	return ""
+}
+
+// slugify lowercases s and joins its alphanumeric runs with sep
+func slugify(s, sep string) string {
+	return strings.Trim(nonAlnumRe.ReplaceAllString(strings.ToLower(s), sep), sep)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	stdlog "log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// routeTable is the spec's paths compiled into a trie once per (re)load.
+// literal segments win over templated ones, so /users/me beats /users/{id}
+// whatever order the spec lists them in
+type routeTable struct {
+	root *routeNode
+}
+
+type routeNode struct {
+	literals  map[string]*routeNode
+	templates []*templateEdge // most literal characters first
+	pattern   string
+	params    []pathParam // the pattern's parameters, in path order
+	item      *openapi3.PathItem
+	channel   *asyncChannel // AsyncAPI channels share the trie
+}
+
+// templateEdge is a path segment with parameters in it, like {id},
+// {name}.json or a matrix ;id=5. patterns whose segments compile to the same
+// regexp share an edge, so parameter names live on the node a pattern ends
+// at: /users/{id} and /users/{userId}/posts each keep their own
+type templateEdge struct {
+	re       *regexp.Regexp
+	literals int
+	node     *routeNode
+}
+
+type pathParam struct {
+	name    string
+	style   string
+	explode bool
+}
+
+func newRouteTable(doc *openapi3.T) *routeTable {
+	t := &routeTable{root: &routeNode{}}
+	if doc == nil || doc.Paths == nil {
+		return t
+	}
+	for _, pattern := range doc.Paths.InMatchingOrder() {
+		t.add(pattern, doc.Paths.Value(pattern))
+	}
+	return t
+}
+
+func (t *routeTable) add(pattern string, item *openapi3.PathItem) {
+	node, params, err := t.insert(pattern, pathParamStyles(item))
+	if err != nil {
+		stdlog.Printf("warning: skipping path %s: %v", pattern, err)
+		return
+	}
+	node.pattern, node.params, node.item = pattern, params, item
+}
+
+// newChannelTable compiles AsyncAPI channel addresses the same way
+func newChannelTable(channels []*asyncChannel) *routeTable {
+	t := &routeTable{root: &routeNode{}}
+	for _, ch := range channels {
+		node, params, err := t.insert(ch.address, nil)
+		if err != nil {
+			stdlog.Printf("warning: skipping channel %s: %v", ch.address, err)
+			continue
+		}
+		node.pattern, node.params, node.channel = ch.address, params, ch
+	}
+	return t
+}
+
+// insert walks pattern into the trie, adding nodes as needed, and returns
+// the node it ends at with the pattern's parameters
+func (t *routeTable) insert(pattern string, styles map[string]pathParam) (*routeNode, []pathParam, error) {
+	node := t.root
+	var params []pathParam
+	for _, seg := range splitPath(pattern) {
+		if !strings.Contains(seg, "{") {
+			if node.literals == nil {
+				node.literals = map[string]*routeNode{}
+			}
+			next := node.literals[seg]
+			if next == nil {
+				next = &routeNode{}
+				node.literals[seg] = next
+			}
+			node = next
+			continue
+		}
+		edge, segParams, err := compileSegment(seg, styles)
+		if err != nil {
+			return nil, nil, err
+		}
+		params = append(params, segParams...)
+		var existing *templateEdge
+		for _, e := range node.templates {
+			if e.re.String() == edge.re.String() {
+				existing = e
+				break
+			}
+		}
+		if existing == nil {
+			edge.node = &routeNode{}
+			node.templates = append(node.templates, edge)
+			sort.SliceStable(node.templates, func(i, j int) bool {
+				a, b := node.templates[i], node.templates[j]
+				if a.literals != b.literals {
+					return a.literals > b.literals
+				}
+				return a.re.String() < b.re.String()
+			})
+			existing = edge
+		}
+		node = existing.node
+	}
+	return node, params, nil
+}
+
+// pathParamStyles collects how each path parameter is serialized, from the
+// path item and its operations
+func pathParamStyles(item *openapi3.PathItem) map[string]pathParam {
+	styles := map[string]pathParam{}
+	collect := func(params openapi3.Parameters) {
+		for _, ref := range params {
+			if ref == nil || ref.Value == nil || ref.Value.In != openapi3.ParameterInPath {
+				continue
+			}
+			if _, ok := styles[ref.Value.Name]; ok {
+				continue
+			}
+			p := pathParam{name: ref.Value.Name, style: openapi3.SerializationSimple}
+			if sm, err := ref.Value.SerializationMethod(); err == nil {
+				p.style, p.explode = sm.Style, sm.Explode
+			}
+			styles[p.name] = p
+		}
+	}
+	collect(item.Parameters)
+	for _, op := range item.Operations() {
+		collect(op.Parameters)
+	}
+	return styles
+}
+
+// compileSegment turns a templated segment into a regexp matching one
+// request path segment, and the parameters its groups capture
+func compileSegment(seg string, styles map[string]pathParam) (*templateEdge, []pathParam, error) {
+	edge := &templateEdge{}
+	var params []pathParam
+	var b strings.Builder
+	b.WriteString("^")
+	rest := seg
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, nil, fmt.Errorf("unclosed { in %q", seg)
+		}
+		b.WriteString(regexp.QuoteMeta(rest[:start]))
+		edge.literals += start
+		name := rest[start+1 : start+end]
+		p, ok := styles[name]
+		if !ok {
+			p = pathParam{name: name, style: openapi3.SerializationSimple}
+		}
+		switch p.style {
+		case openapi3.SerializationLabel:
+			b.WriteString(`\.([^/]+)`)
+		case openapi3.SerializationMatrix:
+			b.WriteString(";" + regexp.QuoteMeta(name) + `=([^/]*)`)
+		default:
+			b.WriteString(`([^/]+)`)
+		}
+		params = append(params, p)
+		rest = rest[start+end+1:]
+	}
+	b.WriteString(regexp.QuoteMeta(rest))
+	b.WriteString("$")
+	edge.literals += len(rest)
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	edge.re = re
+	return edge, params, nil
+}
+
+// lookup finds the path item for a request path. with a method, only path
+// items with that operation count, so GET /users/me falls through to
+// /users/{id} when only the template has a GET
+func (t *routeTable) lookup(reqPath, method string) (*routeNode, map[string]string) {
+	var found []string
+	node := t.root.match(splitPath(reqPath), method, &found)
+	if node == nil {
+		return nil, nil
+	}
+	params := make(map[string]string, len(node.params))
+	for i, p := range node.params {
+		params[p.name] = p.decode(found[i])
+	}
+	return node, params
+}
+
+// match collects the raw values of the templated parts it passes through;
+// they're named once the node a pattern ends at is found
+func (n *routeNode) match(segs []string, method string, found *[]string) *routeNode {
+	if len(segs) == 0 {
+		if n.pattern == "" || (method != "" && (n.item == nil || getOperation(n.item, method) == nil)) {
+			return nil
+		}
+		return n
+	}
+	seg, rest := segs[0], segs[1:]
+	if next := n.literals[seg]; next != nil {
+		if m := next.match(rest, method, found); m != nil {
+			return m
+		}
+	}
+	for _, edge := range n.templates {
+		groups := edge.re.FindStringSubmatch(seg)
+		if groups == nil {
+			continue
+		}
+		mark := len(*found)
+		*found = append(*found, groups[1:]...)
+		if m := edge.node.match(rest, method, found); m != nil {
+			return m
+		}
+		*found = (*found)[:mark]
+	}
+	return nil
+}
+
+// decode normalizes exploded label and matrix values to the simple style's
+// comma separated form
+func (p pathParam) decode(raw string) string {
+	if !p.explode {
+		return raw
+	}
+	switch p.style {
+	case openapi3.SerializationLabel:
+		return strings.ReplaceAll(raw, ".", ",")
+	case openapi3.SerializationMatrix:
+		return strings.ReplaceAll(raw, ";"+p.name+"=", ",")
+	}
+	return raw
+}
+
+func splitPath(p string) []string {
+	return strings.Split(strings.TrimPrefix(p, "/"), "/")
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func routeDoc(paths map[string]*openapi3.PathItem) *openapi3.T {
+	doc := &openapi3.T{OpenAPI: "3.0.3", Paths: openapi3.NewPaths()}
+	for p, item := range paths {
+		doc.Paths.Set(p, item)
+	}
+	return doc
+}
+
+func getItem() *openapi3.PathItem {
+	return &openapi3.PathItem{Get: &openapi3.Operation{}}
+}
+
+func pathParamItem(name, style string, explode bool) *openapi3.PathItem {
+	return &openapi3.PathItem{
+		Get: &openapi3.Operation{},
+		Parameters: openapi3.Parameters{{Value: &openapi3.Parameter{
+			Name: name, In: openapi3.ParameterInPath, Required: true, Style: style, Explode: &explode,
+			Schema: &openapi3.SchemaRef{Value: openapi3.NewStringSchema()},
+		}}},
+	}
+}
+
+func TestRoutePrecedence(t *testing.T) {
+	table := newRouteTable(routeDoc(map[string]*openapi3.PathItem{
+		"/users/{id}":             getItem(),
+		"/users/me":               getItem(),
+		"/users/{id}/posts":       getItem(),
+		"/users/me/posts":         {Post: &openapi3.Operation{}},
+		"/files/{name}.json":      getItem(),
+		"/files/{name}":           getItem(),
+		"/orgs/{org}/repos/{rep}": getItem(),
+	}))
+
+	tests := []struct {
+		path, method, pattern string
+	}{
+		{"/users/me", "GET", "/users/me"},
+		{"/users/42", "GET", "/users/{id}"},
+		{"/users/me/posts", "POST", "/users/me/posts"},
+		// only the template has a GET, so the literal falls through
+		{"/users/me/posts", "GET", "/users/{id}/posts"},
+		{"/files/report.json", "GET", "/files/{name}.json"},
+		{"/files/report.csv", "GET", "/files/{name}"},
+		{"/orgs/acme/repos/api", "GET", "/orgs/{org}/repos/{rep}"},
+		{"/users/me", "", "/users/me"},
+		{"/users", "GET", ""},
+		{"/users/42/comments", "GET", ""},
+	}
+	for _, tt := range tests {
+		node, _ := table.lookup(tt.path, tt.method)
+		got := ""
+		if node != nil {
+			got = node.pattern
+		}
+		if got != tt.pattern {
+			t.Errorf("%s %s: matched %q, want %q", tt.method, tt.path, got, tt.pattern)
+		}
+	}
+}
+
+func TestRouteParams(t *testing.T) {
+	table := newRouteTable(routeDoc(map[string]*openapi3.PathItem{
+		"/orgs/{org}/repos/{repo}": getItem(),
+		"/files/{name}.json":       getItem(),
+		"/label/{ids}":             pathParamItem("ids", openapi3.SerializationLabel, true),
+		"/matrix/{ids}":            pathParamItem("ids", openapi3.SerializationMatrix, true),
+		"/plain/{ids}":             pathParamItem("ids", openapi3.SerializationMatrix, false),
+	}))
+
+	tests := []struct {
+		path   string
+		params map[string]string
+	}{
+		{"/orgs/acme/repos/api", map[string]string{"org": "acme", "repo": "api"}},
+		{"/files/report.json", map[string]string{"name": "report"}},
+		{"/label/.1.2.3", map[string]string{"ids": "1,2,3"}},
+		{"/matrix/;ids=1;ids=2", map[string]string{"ids": "1,2"}},
+		{"/plain/;ids=1,2", map[string]string{"ids": "1,2"}},
+	}
+	for _, tt := range tests {
+		node, params := table.lookup(tt.path, "GET")
+		if node == nil {
+			t.Errorf("%s: no match", tt.path)
+			continue
+		}
+		if len(params) != len(tt.params) {
+			t.Errorf("%s: params %v, want %v", tt.path, params, tt.params)
+			continue
+		}
+		for k, v := range tt.params {
+			if params[k] != v {
+				t.Errorf("%s: %s = %q, want %q", tt.path, k, params[k], v)
+			}
+		}
+	}
+}
+
+// patterns that share a templated segment each keep their own parameter
+// names and styles, however the spec's paths are ordered
+func TestRouteParamNamesPerPattern(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		table := newRouteTable(routeDoc(map[string]*openapi3.PathItem{
+			"/users/{id}":              getItem(),
+			"/users/{userId}/posts":    getItem(),
+			"/users/{uid}/posts/{pid}": getItem(),
+			"/tags/{tags}":             pathParamItem("tags", openapi3.SerializationLabel, false),
+			"/tags/{list}/items":       pathParamItem("list", openapi3.SerializationLabel, true),
+		}))
+
+		tests := []struct {
+			path   string
+			params map[string]string
+		}{
+			{"/users/5", map[string]string{"id": "5"}},
+			{"/users/5/posts", map[string]string{"userId": "5"}},
+			{"/users/5/posts/9", map[string]string{"uid": "5", "pid": "9"}},
+			{"/tags/.a.b", map[string]string{"tags": "a.b"}},
+			{"/tags/.a.b/items", map[string]string{"list": "a,b"}},
+		}
+		for _, tt := range tests {
+			_, params := table.lookup(tt.path, "GET")
+			if len(params) != len(tt.params) {
+				t.Fatalf("%s: params %v, want %v", tt.path, params, tt.params)
+			}
+			for k, v := range tt.params {
+				if params[k] != v {
+					t.Fatalf("%s: params %v, want %v", tt.path, params, tt.params)
+				}
+			}
+		}
+	}
+}
+
+func TestRouteUnclosedTemplate(t *testing.T) {
+	table := newRouteTable(routeDoc(map[string]*openapi3.PathItem{
+		"/users/{id":  getItem(),
+		"/users/{id}": getItem(),
+	}))
+	if node, _ := table.lookup("/users/5", "GET"); node == nil || node.pattern != "/users/{id}" {
+		t.Errorf("the well-formed path should still match, got %v", node)
+	}
+}
+
+func TestChannelLookup(t *testing.T) {
+	chat := &asyncChannel{address: "/rooms/{room}/chat"}
+	prices := &asyncChannel{address: "/prices"}
+	api := &AsyncAPI{Channels: []*asyncChannel{chat, prices}}
+	api.routes = newChannelTable(api.Channels)
+
+	tests := []struct {
+		path string
+		want *asyncChannel
+	}{
+		{"/rooms/7/chat", chat},
+		{"/prices", prices},
+		{"/rooms/7", nil},
+		{"/nope", nil},
+	}
+	for _, tt := range tests {
+		if got := api.channel(tt.path); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+// thousandPathDoc has 1,000 paths: literal collections, templated items and
+// nested sub-resources, like a large real-world spec
+func thousandPathDoc() *openapi3.T {
+	paths := map[string]*openapi3.PathItem{}
+	for i := 0; len(paths) < 1000; i++ {
+		paths[fmt.Sprintf("/resource%d", i)] = getItem()
+		paths[fmt.Sprintf("/resource%d/{id}", i)] = getItem()
+		paths[fmt.Sprintf("/resource%d/{id}/children/{child}", i)] = getItem()
+		paths[fmt.Sprintf("/resource%d/search", i)] = getItem()
+	}
+	return routeDoc(paths)
+}
+
+func BenchmarkRouteLookup(b *testing.B) {
+	table := newRouteTable(thousandPathDoc())
+	reqs := []string{"/resource0", "/resource125/42", "/resource249/42/children/7", "/resource249/search", "/missing/path"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		table.lookup(reqs[i%len(reqs)], "GET")
+	}
+}
+
+func BenchmarkRouteTableBuild(b *testing.B) {
+	doc := thousandPathDoc()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newRouteTable(doc)
+	}
+}
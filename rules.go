@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// RulesFile represents a user-defined field inference rules file
+type RulesFile struct {
+	Rules []FieldRule `yaml:"rules"`
+}
+
+// FieldRule maps a property name pattern to a faker function or template
+type FieldRule struct {
+	Name     string            `yaml:"name"`     // regex matched against the property name
+	Type     string            `yaml:"type"`     // optional schema type scope (string, integer, number)
+	Format   string            `yaml:"format"`   // optional schema format scope
+	Parent   string            `yaml:"parent"`   // optional regex matched against the parent schema name
+	Faker    string            `yaml:"faker"`    // gofakeit function name, e.g. "uuid", "number"
+	Params   map[string]string `yaml:"params"`   // params passed to the faker function
+	Template string            `yaml:"template"` // gofakeit template, e.g. "1Z###?{lexify:???}"
+
+	nameRe   *regexp.Regexp
+	parentRe *regexp.Regexp
+}
+
+// fieldRules are evaluated before the built-in name heuristics. hot reloads
+// swap them while requests read them, so they're behind rulesMu
+var (
+	fieldRules []*FieldRule
+	rulesMu    sync.RWMutex
+)
+
+// rulesFiles are the config and rules files the rules came from, watched
+// along with the spec
+var rulesFiles []string
+
+// loadFieldRules reads and compiles a rules file
+func loadFieldRules(path string) ([]*FieldRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rf RulesFile
+	if err := yaml.Unmarshal(data, &rf); err != nil {
+		return nil, err
+	}
+
+	rules := make([]*FieldRule, 0, len(rf.Rules))
+	for i := range rf.Rules {
+		rule := &rf.Rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d: name is required", i+1)
+		}
+		if rule.Faker == "" && rule.Template == "" {
+			return nil, fmt.Errorf("rule %d (%s): needs a faker function or a template", i+1, rule.Name)
+		}
+		if rule.Faker != "" && gofakeit.GetFuncLookup(rule.Faker) == nil {
+			return nil, fmt.Errorf("rule %d (%s): unknown faker function %q", i+1, rule.Name, rule.Faker)
+		}
+		rule.nameRe, err = regexp.Compile("(?i)" + rule.Name)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: invalid name pattern: %w", i+1, err)
+		}
+		if rule.Parent != "" {
+			rule.parentRe, err = regexp.Compile("(?i)" + rule.Parent)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: invalid parent pattern: %w", i+1, err)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// applyConfigRules loads the rules file referenced from the config, resolved
+// relative to the config file
+func applyConfigRules(cfg *Config) error {
+	if cfg == nil || cfg.Rules == "" {
+		setFieldRules(nil)
+		return nil
+	}
+	path := cfg.Rules
+	if !filepath.IsAbs(path) && cfg.path != "" {
+		path = filepath.Join(filepath.Dir(cfg.path), path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	rules, err := loadFieldRules(path)
+	if err != nil {
+		return fmt.Errorf("failed to load rules %s: %w", path, err)
+	}
+	setFieldRules(rules, cfg.path, path)
+	return nil
+}
+
+// reloadFieldRules reads the config and its rules file again when the spec
+// hot reloads. if they don't load, the rules in use are kept
+func reloadFieldRules() error {
+	return applyConfigRules(loadConfig())
+}
+
+func setFieldRules(rules []*FieldRule, files ...string) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	fieldRules, rulesFiles = rules, files
+}
+
+// watchedRulesFiles are the files whose changes reload the rules
+func watchedRulesFiles() []string {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	return rulesFiles
+}
+
+func (rule *FieldRule) matches(propName, parent string, schema *openapi3.Schema, typ string) bool {
+	if rule.Type != "" && rule.Type != typ {
+		return false
+	}
+	if rule.Type == "" && typ != "string" && typ != "integer" && typ != "number" {
+		return false
+	}
+	if rule.Format != "" && rule.Format != schema.Format {
+		return false
+	}
+	if rule.parentRe != nil && !rule.parentRe.MatchString(parent) {
+		return false
+	}
+	return rule.nameRe.MatchString(propName)
+}
+
+// generateByRules returns a value from the first matching user rule
+func generateByRules(propName, parent string, schema *openapi3.Schema, rng *rand.Rand) (interface{}, bool) {
+	rulesMu.RLock()
+	rules := fieldRules
+	rulesMu.RUnlock()
+	if len(rules) == 0 || len(schema.Enum) > 0 {
+		return nil, false
+	}
+	types := schema.Type.Slice()
+	if len(types) == 0 {
+		return nil, false
+	}
+	typ := types[0]
+
+	for _, rule := range rules {
+		if !rule.matches(propName, parent, schema, typ) {
+			continue
+		}
+		raw, err := rule.generate(rng)
+		if err != nil {
+			logStrictWarning("rules", fmt.Sprintf("%s: %v", rule.Name, err))
+			continue
+		}
+		if v, ok := coerceRuleValue(raw, typ); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (rule *FieldRule) generate(rng *rand.Rand) (interface{}, error) {
+	faker := gofakeit.New(uint64(rng.Int63()))
+	if rule.Template != "" {
+		return faker.Generate(rule.Template)
+	}
+	info := gofakeit.GetFuncLookup(rule.Faker)
+	params := gofakeit.NewMapParams()
+	for k, v := range rule.Params {
+		params.Add(k, v)
+	}
+	return info.Generate(faker, params, info)
+}
+
+// coerceRuleValue converts a rule's output to the schema's type
+func coerceRuleValue(raw interface{}, typ string) (interface{}, bool) {
+	switch typ {
+	case "integer":
+		if n, ok := toFloat64(raw); ok {
+			return int64(n), true
+		}
+		i, err := strconv.ParseInt(strings.TrimSpace(fmt.Sprintf("%v", raw)), 10, 64)
+		return i, err == nil
+	case "number":
+		if n, ok := toFloat64(raw); ok {
+			return n, true
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprintf("%v", raw)), 64)
+		return f, err == nil
+	default:
+		return fmt.Sprintf("%v", raw), true
+	}
+}
+
+// schemaName returns the component name of a referenced schema, or its title
+func schemaName(ref *openapi3.SchemaRef) string {
+	if ref == nil {
+		return ""
+	}
+	if ref.Ref != "" {
+		return ref.Ref[strings.LastIndex(ref.Ref, "/")+1:]
+	}
+	if ref.Value != nil {
+		return ref.Value.Title
+	}
+	return ""
+}
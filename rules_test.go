@@ -0,0 +1,186 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func testRules(t *testing.T, content string) []*FieldRule {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := loadFieldRules(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
+
+func TestFieldRuleMatches(t *testing.T) {
+	rules := testRules(t, `rules:
+  - {name: "^iban$", faker: achaccount}
+  - {name: "_cents$", type: integer, faker: number}
+  - {name: "^code$", format: uuid, faker: uuid}
+  - {name: "^tracking_number$", parent: "^Shipment$", template: "1Z###"}
+`)
+	iban, cents, code, tracking := rules[0], rules[1], rules[2], rules[3]
+	str := openapi3.NewStringSchema()
+	uuid := openapi3.NewUUIDSchema()
+	integer := openapi3.NewIntegerSchema()
+
+	tests := []struct {
+		name   string
+		rule   *FieldRule
+		prop   string
+		parent string
+		schema *openapi3.Schema
+		typ    string
+		want   bool
+	}{
+		{"name", iban, "iban", "", str, "string", true},
+		{"name is case-insensitive", iban, "IBAN", "", str, "string", true},
+		{"name is a regex, not a substring", iban, "iban_country", "", str, "string", false},
+		{"unscoped rules skip objects", iban, "iban", "", openapi3.NewObjectSchema(), "object", false},
+		{"unscoped rules take integers", iban, "iban", "", integer, "integer", true},
+		{"type scope", cents, "price_cents", "", integer, "integer", true},
+		{"type scope rejects other types", cents, "price_cents", "", str, "string", false},
+		{"format scope", code, "code", "", uuid, "string", true},
+		{"format scope rejects other formats", code, "code", "", str, "string", false},
+		{"parent scope", tracking, "tracking_number", "Shipment", str, "string", true},
+		{"parent scope rejects other parents", tracking, "tracking_number", "Order", str, "string", false},
+		{"parent scope rejects no parent", tracking, "tracking_number", "", str, "string", false},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.matches(tt.prop, tt.parent, tt.schema, tt.typ); got != tt.want {
+			t.Errorf("%s: matched %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadFieldRulesErrors(t *testing.T) {
+	tests := []string{
+		"rules:\n  - {faker: uuid}\n",
+		"rules:\n  - {name: x}\n",
+		"rules:\n  - {name: x, faker: notafakerfunc}\n",
+		"rules:\n  - {name: '(', faker: uuid}\n",
+		"rules:\n  - {name: x, parent: '(', faker: uuid}\n",
+	}
+	for _, content := range tests {
+		file := filepath.Join(t.TempDir(), "rules.yaml")
+		os.WriteFile(file, []byte(content), 0o644)
+		if _, err := loadFieldRules(file); err == nil {
+			t.Errorf("expected an error for %q", content)
+		}
+	}
+}
+
+func TestCoerceRuleValue(t *testing.T) {
+	tests := []struct {
+		raw  interface{}
+		typ  string
+		want interface{}
+		ok   bool
+	}{
+		{"42", "integer", int64(42), true},
+		{" 42 ", "integer", int64(42), true},
+		{42.9, "integer", int64(42), true},
+		{"4.2", "integer", int64(0), false},
+		{"abc", "integer", int64(0), false},
+		{"4.5", "number", 4.5, true},
+		{7, "number", 7.0, true},
+		{"abc", "number", 0.0, false},
+		{123, "string", "123", true},
+		{"DE89", "string", "DE89", true},
+	}
+	for _, tt := range tests {
+		got, ok := coerceRuleValue(tt.raw, tt.typ)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("coerce %#v to %s: got %#v, %v, want %#v, %v", tt.raw, tt.typ, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGenerateByRules(t *testing.T) {
+	defer setFieldRules(nil)
+	setFieldRules(testRules(t, `rules:
+  - {name: "^sku$", template: "SKU-####"}
+  - {name: "^sku$", template: "never used, the first match wins"}
+  - {name: "_cents$", type: integer, faker: number, params: {min: "100", max: "199"}}
+  - {name: "^size$", template: "not a number"}
+`))
+	rng := rand.New(rand.NewSource(1))
+
+	v, ok := generateByRules("sku", "", openapi3.NewStringSchema(), rng)
+	if s, _ := v.(string); !ok || !regexp.MustCompile(`^SKU-\d{4}$`).MatchString(s) {
+		t.Errorf("sku: got %#v, %v", v, ok)
+	}
+	v, ok = generateByRules("price_cents", "", openapi3.NewIntegerSchema(), rng)
+	if n, _ := v.(int64); !ok || n < 100 || n > 199 {
+		t.Errorf("price_cents: got %#v, %v", v, ok)
+	}
+	// output that doesn't fit the type falls back to the built-in heuristics
+	if v, ok := generateByRules("size", "", openapi3.NewIntegerSchema(), rng); ok {
+		t.Errorf("size: got %#v, want no rule value", v)
+	}
+	enum := openapi3.NewStringSchema()
+	enum.Enum = []interface{}{"a", "b"}
+	if v, ok := generateByRules("sku", "", enum, rng); ok {
+		t.Errorf("enum field: got %#v, want the enum to win", v)
+	}
+}
+
+func TestRulesReloadWithSpec(t *testing.T) {
+	defer setFieldRules(nil)
+	dir := t.TempDir()
+	t.Chdir(dir)
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".portblock.yaml", "rules: rules.yaml\n")
+	write("rules.yaml", "rules:\n  - {name: \"^sku$\", template: \"OLD-###\"}\n")
+	write("api.yaml", `openapi: 3.0.3
+info: {title: skus, version: "1"}
+paths:
+  /items:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {type: object, properties: {sku: {type: string}}}
+`)
+	if err := applyConfigRules(loadConfig()); err != nil {
+		t.Fatal(err)
+	}
+	m := &specMount{file: filepath.Join(dir, "api.yaml")}
+	s, err := loadMockServer(m, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := s.watch(m.file, m.source)
+	defer stop()
+
+	sku := func() string {
+		v, _ := generateByRules("sku", "", openapi3.NewStringSchema(), rand.New(rand.NewSource(1)))
+		str, _ := v.(string)
+		return str
+	}
+	if got := sku(); !regexp.MustCompile(`^OLD-\d{3}$`).MatchString(got) {
+		t.Fatalf("sku %q, want OLD-###", got)
+	}
+	write("rules.yaml", "rules:\n  - {name: \"^sku$\", template: \"NEW-###\"}\n")
+	waitFor(t, "the edited rules to reload", func() bool {
+		return regexp.MustCompile(`^NEW-\d{3}$`).MatchString(sku())
+	})
+}
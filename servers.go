@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// basePathFlag mounts the mock under a fixed prefix instead of the base paths
+// the spec's servers declare
+var basePathFlag string
+
+// serverBasePaths are the path parts of the spec's server URLs, longest
+// first. server variables expand to each enum value, or just the default
+func serverBasePaths(doc *openapi3.T) []string {
+	seen := map[string]bool{}
+	var paths []string
+	for _, server := range doc.Servers {
+		if server == nil {
+			continue
+		}
+		for _, p := range expandServerPath(serverPath(server.URL), server.Variables) {
+			p = cleanBasePath(p)
+			if p != "" && !seen[p] {
+				seen[p] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+	sort.SliceStable(paths, func(i, j int) bool { return len(paths[i]) > len(paths[j]) })
+	return paths
+}
+
+// serverPath drops the scheme and host of a server URL
+func serverPath(u string) string {
+	if _, rest, ok := strings.Cut(u, "://"); ok {
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			return rest[i:]
+		}
+		return ""
+	}
+	return u
+}
+
+func expandServerPath(path string, vars map[string]*openapi3.ServerVariable) []string {
+	start := strings.IndexByte(path, '{')
+	if start < 0 {
+		return []string{path}
+	}
+	end := strings.IndexByte(path[start:], '}')
+	if end < 0 {
+		return []string{path}
+	}
+	name := path[start+1 : start+end]
+	var values []string
+	if v := vars[name]; v != nil {
+		values = append(values, v.Default)
+		for _, e := range v.Enum {
+			if e != v.Default {
+				values = append(values, e)
+			}
+		}
+	}
+	var out []string
+	for _, value := range values {
+		out = append(out, expandServerPath(path[:start]+value+path[start+end+1:], vars)...)
+	}
+	return out
+}
+
+func cleanBasePath(p string) string {
+	p = strings.TrimRight(strings.TrimSpace(p), "/")
+	if p != "" && !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return p
+}
+
+// basePaths is what the mock is mounted under: --base-path, or the servers'
+func basePaths(doc *openapi3.T) []string {
+	if basePathFlag != "" {
+		if p := cleanBasePath(basePathFlag); p != "" {
+			return []string{p}
+		}
+		return nil
+	}
+	return serverBasePaths(doc)
+}
+
+// primaryBasePath is the base path routes are listed under: --base-path, or
+// the first server's with its default variables
+func primaryBasePath(doc *openapi3.T) string {
+	if basePathFlag != "" || len(doc.Servers) == 0 || doc.Servers[0] == nil {
+		return cleanBasePath(basePathFlag)
+	}
+	if paths := expandServerPath(serverPath(doc.Servers[0].URL), doc.Servers[0].Variables); len(paths) > 0 {
+		return cleanBasePath(paths[0])
+	}
+	return ""
+}
+
+// newValidationRouter builds the request validation router. base paths are
+// stripped before requests get there, so it matches on the spec's paths alone
+func newValidationRouter(doc *openapi3.T) (routers.Router, error) {
+	unmounted := *doc
+	unmounted.Servers = nil
+	return gorillamux.NewRouter(&unmounted)
+}
+
+type basePathKey struct{}
+
+// mount strips the spec's prefix and base path off the request. paths from
+// the servers are optional, so clients calling the bare spec paths keep
+// working; --base-path is required. portblock's own /__portblock/ endpoints
+// are never mounted
+func (s *MockServer) mount(r *http.Request) (*http.Request, bool) {
+	if strings.HasPrefix(r.URL.Path, "/__portblock/") {
+		return r, true
+	}
+	path, raw, mounted := r.URL.Path, r.URL.RawPath, ""
+	if s.prefix != "" {
+		rest, ok := cutBasePath(path, s.prefix)
+		if !ok {
+			return r, false
+		}
+		path, raw, mounted = rest, strings.TrimPrefix(raw, s.prefix), s.prefix
+	}
+	// a --base-path of "/" cleans to nothing, which mounts at the root
+	matched := basePathFlag == "" || len(s.basePaths) == 0
+	for _, base := range s.basePaths {
+		if rest, ok := cutBasePath(path, base); ok {
+			path, raw, mounted = rest, strings.TrimPrefix(raw, base), mounted+base
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return r, false
+	}
+	if mounted == "" {
+		return r, true
+	}
+	r2 := r.WithContext(context.WithValue(r.Context(), basePathKey{}, mounted))
+	u := *r.URL
+	u.Path = path
+	u.RawPath = ""
+	if raw != "" && raw != r.URL.RawPath {
+		u.RawPath = raw
+	}
+	r2.URL = &u
+	return r2, true
+}
+
+// unmountedDetail says where requests have to start when they miss the mount
+func (s *MockServer) unmountedDetail() string {
+	start := s.prefix
+	if basePathFlag != "" && len(s.basePaths) > 0 {
+		start += s.basePaths[0]
+	}
+	if start == "" {
+		return "route not found"
+	}
+	return "route not found — requests must start with " + start
+}
+
+// cutBasePath strips base off p when p is base itself or a path below it
+func cutBasePath(p, base string) (string, bool) {
+	rest, ok := strings.CutPrefix(p, base)
+	if !ok || (rest != "" && rest[0] != '/') {
+		return "", false
+	}
+	if rest == "" {
+		rest = "/"
+	}
+	return rest, true
+}
+
+// mountedPath is the request path as the client sent it, base path included
+func mountedPath(r *http.Request) string {
+	base, _ := r.Context().Value(basePathKey{}).(string)
+	return base + r.URL.Path
+}
@@ -0,0 +1,760 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"math/rand"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/spf13/cobra"
+)
+
+const (
+	nsEnvelope11 = "http://schemas.xmlsoap.org/soap/envelope/"
+	nsEnvelope12 = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+func runSOAP(cmd *cobra.Command, args []string) error {
+	wsdlFile := args[0]
+
+	cfg := loadConfig()
+	applyConfig(cfg)
+	if err := applyConfigRules(cfg); err != nil {
+		return err
+	}
+	if err := applyDataFlags(); err != nil {
+		return err
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	mock, err := newSOAPMock(wsdlFile, seed)
+	if err != nil {
+		return fmt.Errorf("failed to load WSDL: %w", err)
+	}
+
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: http.HandlerFunc(mock.handle)}
+	if err := configureTLS(srv); err != nil {
+		return err
+	}
+
+	fmt.Println(renderBanner("soap", wsdlFile, port, seed, delay, chaos, false))
+	fmt.Print(renderRoutes(mock.routes()))
+	fmt.Print(renderReady(port))
+
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		<-sigCh
+		fmt.Print(renderShutdown())
+		srv.Shutdown(context.Background())
+	}()
+
+	return serveHTTP(srv)
+}
+
+// soapMock answers SOAP requests for the operations of a WSDL with generated
+// response envelopes, or faults
+type soapMock struct {
+	wsdl       []byte
+	seed       int64
+	operations []*soapOperation
+	paths      map[string]bool
+	schemas    map[*xsdType]*openapi3.Schema
+}
+
+func newSOAPMock(path string, seed int64) (*soapMock, error) {
+	ops, err := loadWSDL(path)
+	if err != nil {
+		return nil, err
+	}
+	wsdl, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &soapMock{
+		wsdl:       wsdl,
+		seed:       seed,
+		operations: ops,
+		paths:      map[string]bool{},
+		schemas:    map[*xsdType]*openapi3.Schema{},
+	}
+	// build every schema up front, handlers only read them
+	for _, op := range ops {
+		m.paths[op.path] = true
+		if op.output != nil {
+			m.schema(op.output.typ)
+		}
+		for _, el := range op.faults {
+			if el != nil {
+				m.schema(el.typ)
+			}
+		}
+	}
+	return m, nil
+}
+
+// soapFault is a fault on its way to the client. detail is the fault's
+// element from the WSDL, or validation errors
+type soapFault struct {
+	sender  bool // Client/Sender rather than Server/Receiver
+	reason  string
+	name    string
+	element *xsdElement
+	errors  []map[string]string
+	request *nsNode
+}
+
+func (m *soapMock) handle(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	if r.Method == http.MethodGet && r.URL.Query().Has("wsdl") {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Write(m.wsdl)
+		logRequest("GET", r.URL.Path+"?wsdl", 200, time.Since(start))
+		return
+	}
+	if !m.paths[r.URL.Path] {
+		http.NotFound(w, r)
+		logRequest(r.Method, r.URL.Path, 404, time.Since(start))
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "SOAP endpoints only accept POST", http.StatusMethodNotAllowed)
+		logRequest(r.Method, r.URL.Path, 405, time.Since(start))
+		return
+	}
+
+	version := "1.1"
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/soap+xml" {
+		version = "1.2"
+	}
+	data, err := readRequestBody(w, r)
+	if err != nil {
+		status := m.writeFault(w, version, nil, soapFault{sender: true, reason: err.Error()})
+		logSOAP(r.URL.Path, status, "malformed", time.Since(start))
+		return
+	}
+	env, err := parseNamespacedXML(data)
+	if err == nil && !env.is(nsEnvelope11, "Envelope") && !env.is(nsEnvelope12, "Envelope") {
+		err = fmt.Errorf("root element is not a SOAP Envelope")
+	}
+	if err != nil {
+		status := m.writeFault(w, version, nil, soapFault{sender: true, reason: "malformed envelope: " + err.Error()})
+		logSOAP(r.URL.Path, status, "malformed", time.Since(start))
+		return
+	}
+	if env.XMLName.Space == nsEnvelope12 {
+		version = "1.2"
+	} else {
+		version = "1.1"
+	}
+	var payload *nsNode
+	if body := env.child(env.XMLName.Space, "Body"); body != nil && len(body.Children) > 0 {
+		payload = body.Children[0]
+	}
+	if payload == nil {
+		status := m.writeFault(w, version, nil, soapFault{sender: true, reason: "envelope has no Body content"})
+		logSOAP(r.URL.Path, status, "malformed", time.Since(start))
+		return
+	}
+
+	action := soapAction(r)
+	op := m.route(r.URL.Path, version, action, payload.XMLName)
+	if op == nil {
+		reason := fmt.Sprintf("no operation accepts {%s}%s", payload.XMLName.Space, payload.XMLName.Local)
+		if action != "" {
+			reason = "unknown SOAPAction " + action
+		}
+		status := m.writeFault(w, version, nil, soapFault{sender: true, reason: reason})
+		logSOAP(r.URL.Path, status, "unknown", time.Since(start))
+		return
+	}
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if chaos {
+		chaosRng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		if chaosRng.Float64() < 0.1 {
+			m.writeFault(w, version, op, soapFault{reason: "chaos mode struck 💥"})
+			logChaos("SOAP", op.name, time.Since(start))
+			return
+		}
+		if chaosRng.Float64() < 0.2 {
+			time.Sleep(time.Duration(chaosRng.Intn(2000)) * time.Millisecond)
+		}
+	}
+
+	if errs := validateElement(op.input, payload, op.input.name.Local); len(errs) > 0 {
+		status := m.writeFault(w, version, op, soapFault{sender: true, reason: "request does not match the WSDL", errors: errs})
+		logSOAP(op.name, status, "invalid", time.Since(start))
+		return
+	}
+
+	// Prefer: fault=UserNotFound returns a declared fault, like Prefer: code=
+	if name := parsePreference(r, "fault"); name != "" {
+		f := soapFault{name: name, reason: name, request: payload}
+		if el, ok := op.faults[name]; ok {
+			f.element = el
+		} else if strings.EqualFold(name, "client") || strings.EqualFold(name, "sender") {
+			f.sender = true
+			f.reason = "forced client fault"
+		} else {
+			f.reason = "forced server fault"
+		}
+		status := m.writeFault(w, version, op, f)
+		logSOAP(op.name, status, name, time.Since(start))
+		return
+	}
+
+	if op.output == nil {
+		w.WriteHeader(http.StatusAccepted)
+		logSOAP(op.name, http.StatusAccepted, "", time.Since(start))
+		return
+	}
+	rng := seededRng(m.seed, op.name+"#"+soapRequestKey(payload))
+	value := m.generate(op.output, rng)
+	echoRequest(value, payload)
+	m.writeEnvelope(w, version, http.StatusOK, op.output, value, rng)
+	logSOAP(op.name, http.StatusOK, "", time.Since(start))
+}
+
+// soapAction reads the SOAPAction header (1.1) or the action parameter of the
+// content type (1.2)
+func soapAction(r *http.Request) string {
+	if v := r.Header.Get("SOAPAction"); v != "" {
+		return strings.Trim(v, `"`)
+	}
+	_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return params["action"]
+}
+
+// route finds the operation by SOAPAction, falling back to the body element
+// when the action is empty or the binding doesn't declare one
+func (m *soapMock) route(path, version, action string, element xml.Name) *soapOperation {
+	var byElement *soapOperation
+	for _, op := range m.operations {
+		if op.path != path || op.version != version {
+			continue
+		}
+		if action != "" && op.action == action {
+			return op
+		}
+		if op.input.name == element && (action == "" || op.action == "") && byElement == nil {
+			byElement = op
+		}
+	}
+	return byElement
+}
+
+// validateElement checks a request element against its declaration: known
+// children, required ones present, occurrence limits and simple values
+func validateElement(el *xsdElement, n *nsNode, path string) []map[string]string {
+	var errs []map[string]string
+	add := func(field, message string) {
+		errs = append(errs, map[string]string{"field": field, "message": message})
+	}
+	if n.XMLName != el.name {
+		add(path, fmt.Sprintf("expected element {%s}%s", el.name.Space, el.name.Local))
+		return errs
+	}
+	t := el.typ
+	if t == nil {
+		return nil
+	}
+	for _, a := range t.attrs {
+		v, ok := attrValue(n, a.name)
+		if !ok {
+			if a.required {
+				add(path+"/@"+a.name, "required attribute missing")
+			}
+			continue
+		}
+		if msg := checkSimple(a.typ, v); msg != "" {
+			add(path+"/@"+a.name, msg)
+		}
+	}
+	if len(t.elements) == 0 {
+		if t.base != "" {
+			if msg := checkSimple(t, strings.TrimSpace(n.Text)); msg != "" {
+				add(path, msg)
+			}
+		}
+		return errs
+	}
+
+	counts := map[xml.Name]int{}
+	for _, c := range n.Children {
+		var decl *xsdElement
+		for _, child := range t.elements {
+			if child.name == c.XMLName {
+				decl = child
+				break
+			}
+		}
+		if decl == nil {
+			add(path+"/"+c.XMLName.Local, "unexpected element")
+			continue
+		}
+		counts[decl.name]++
+		errs = append(errs, validateElement(decl, c, path+"/"+c.XMLName.Local)...)
+	}
+	for _, child := range t.elements {
+		got := counts[child.name]
+		if child.choice == 0 && got < child.min {
+			add(path+"/"+child.name.Local, "required element missing")
+		}
+		if child.max >= 0 && got > child.max {
+			add(path+"/"+child.name.Local, fmt.Sprintf("at most %d allowed, got %d", child.max, got))
+		}
+	}
+	return errs
+}
+
+func attrValue(n *nsNode, name string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name && a.Name.Space != "xmlns" {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// checkSimple validates a simple value against its builtin type, enumeration
+// and pattern
+func checkSimple(t *xsdType, v string) string {
+	if t == nil {
+		return ""
+	}
+	if len(t.enum) > 0 && !containsString(t.enum, v) {
+		return "must be one of " + strings.Join(t.enum, ", ")
+	}
+	if t.pattern != "" {
+		if re, err := regexp.Compile("^(?:" + t.pattern + ")$"); err == nil && !re.MatchString(v) {
+			return "must match pattern " + t.pattern
+		}
+	}
+	var err error
+	switch xsdSchemaType(t.base) {
+	case "integer":
+		_, err = strconv.ParseInt(v, 10, 64)
+	case "number":
+		_, err = strconv.ParseFloat(v, 64)
+	case "boolean":
+		if v != "true" && v != "false" && v != "1" && v != "0" {
+			err = fmt.Errorf("not a boolean")
+		}
+	}
+	if err != nil {
+		return "invalid " + t.base + " value: " + v
+	}
+	switch t.base {
+	case "date":
+		_, err = time.Parse("2006-01-02", v)
+	case "dateTime":
+		if _, e := time.Parse(time.RFC3339, v); e != nil {
+			_, err = time.Parse("2006-01-02T15:04:05", v)
+		}
+	}
+	if err != nil {
+		return "invalid " + t.base + " value: " + v
+	}
+	return ""
+}
+
+// soapRequestKey is the request's values in document order, so equal requests
+// get equal responses however they're formatted
+func soapRequestKey(n *nsNode) string {
+	var b strings.Builder
+	var walk func(n *nsNode)
+	walk = func(n *nsNode) {
+		b.WriteString(n.XMLName.Local)
+		for _, a := range n.Attrs {
+			if a.Name.Space != "xmlns" && a.Name.Local != "xmlns" {
+				b.WriteString("@" + a.Name.Local + "=" + a.Value)
+			}
+		}
+		if len(n.Children) == 0 {
+			b.WriteString("=" + strings.TrimSpace(n.Text))
+		}
+		for _, c := range n.Children {
+			b.WriteString("(")
+			walk(c)
+			b.WriteString(")")
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// echoRequest copies the request's simple values onto response fields with
+// the same name, up to one wrapper deep, so GetUser(id: 42) returns user 42
+func echoRequest(resp interface{}, req *nsNode) {
+	values := map[string]string{}
+	for _, c := range req.Children {
+		if len(c.Children) == 0 {
+			values[c.XMLName.Local] = strings.TrimSpace(c.Text)
+		}
+	}
+	var fill func(v interface{}, depth int)
+	fill = func(v interface{}, depth int) {
+		obj, ok := v.(map[string]interface{})
+		if !ok || depth > 1 {
+			return
+		}
+		for k, val := range obj {
+			switch val.(type) {
+			case map[string]interface{}:
+				fill(val, depth+1)
+			case []interface{}:
+			default:
+				if req, ok := values[k]; ok {
+					obj[k] = req
+				}
+			}
+		}
+	}
+	fill(resp, 0)
+}
+
+// --------------- generation ---------------
+
+// schema describes a type as an OpenAPI schema, so responses come from the
+// same engine as REST mocks: related fields, name heuristics, rules
+func (m *soapMock) schema(t *xsdType) *openapi3.Schema {
+	if s, ok := m.schemas[t]; ok {
+		return s
+	}
+	if t.simple() {
+		s := xsdSimpleSchema(t)
+		m.schemas[t] = s
+		return s
+	}
+	s := openapi3.NewObjectSchema()
+	s.Title = t.name
+	m.schemas[t] = s
+	for _, a := range t.attrs {
+		s.WithPropertyRef(a.name, openapi3.NewSchemaRef("", m.schema(a.typ)))
+	}
+	if t.base != "" {
+		s.WithPropertyRef("#text", openapi3.NewSchemaRef("", xsdSimpleSchema(t)))
+	}
+	for _, el := range t.elements {
+		item := openapi3.NewSchemaRef("", m.schema(el.typ))
+		if el.max == 1 {
+			s.WithPropertyRef(el.name.Local, item)
+			continue
+		}
+		arr := openapi3.NewArraySchema()
+		arr.Items = item
+		if el.min > 0 {
+			arr.MinItems = uint64(el.min)
+		}
+		if el.max > 0 {
+			max := uint64(el.max)
+			arr.MaxItems = &max
+		}
+		s.WithPropertyRef(el.name.Local, openapi3.NewSchemaRef("", arr))
+	}
+	return s
+}
+
+func xsdSimpleSchema(t *xsdType) *openapi3.Schema {
+	var s *openapi3.Schema
+	switch xsdSchemaType(t.base) {
+	case "integer":
+		s = openapi3.NewIntegerSchema()
+		switch t.base {
+		case "nonNegativeInteger", "unsignedInt", "unsignedLong", "unsignedShort", "unsignedByte":
+			s.WithMin(0)
+		case "positiveInteger":
+			s.WithMin(1)
+		case "negativeInteger", "nonPositiveInteger":
+			s.WithMin(-1000).WithMax(-1)
+		}
+	case "number":
+		s = openapi3.NewFloat64Schema()
+	case "boolean":
+		s = openapi3.NewBoolSchema()
+	default:
+		s = openapi3.NewStringSchema()
+		switch t.base {
+		case "dateTime", "time":
+			s.Format = "date-time"
+		case "date":
+			s.Format = "date"
+		case "anyURI":
+			s.Format = "uri"
+		case "base64Binary":
+			s.Format = "byte"
+		}
+	}
+	for _, v := range t.enum {
+		s.Enum = append(s.Enum, v)
+	}
+	return s
+}
+
+// xsdSchemaType maps an XSD builtin to its JSON Schema type
+func xsdSchemaType(base string) string {
+	switch base {
+	case "int", "integer", "long", "short", "byte", "nonNegativeInteger", "positiveInteger",
+		"negativeInteger", "nonPositiveInteger", "unsignedInt", "unsignedLong", "unsignedShort", "unsignedByte":
+		return "integer"
+	case "decimal", "float", "double":
+		return "number"
+	case "boolean":
+		return "boolean"
+	}
+	return "string"
+}
+
+func (m *soapMock) generate(el *xsdElement, rng *rand.Rand) interface{} {
+	return generateFromSchemaWithName(openapi3.NewSchemaRef("", m.schema(el.typ)), rng, 0, el.name.Local, "", nil)
+}
+
+// --------------- envelopes ---------------
+
+// soapWriter writes elements in declaration order with indentation. prefixes
+// are collected as it goes and declared on the Envelope
+type soapWriter struct {
+	buf      bytes.Buffer
+	prefixes map[string]string
+	order    []string
+	rng      *rand.Rand
+}
+
+func (x *soapWriter) qualified(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	p, ok := x.prefixes[name.Space]
+	if !ok {
+		p = "ns" + strconv.Itoa(len(x.prefixes)+1)
+		x.prefixes[name.Space] = p
+		x.order = append(x.order, name.Space)
+	}
+	return p + ":" + name.Local
+}
+
+func (x *soapWriter) element(el *xsdElement, v interface{}, depth int) {
+	if list, ok := v.([]interface{}); ok {
+		for _, item := range list {
+			x.element(el, item, depth)
+		}
+		return
+	}
+	if el.min == 0 && !complete(el, v) {
+		return
+	}
+	indent := strings.Repeat("  ", depth)
+	tag := x.qualified(el.name)
+	x.buf.WriteString(indent + "<" + tag)
+
+	t := el.typ
+	obj, _ := v.(map[string]interface{})
+	if t != nil && obj != nil {
+		for _, a := range t.attrs {
+			if val, ok := obj[a.name]; ok && val != nil {
+				x.buf.WriteString(" " + a.name + `="`)
+				xml.EscapeText(&x.buf, []byte(xsdValue(a.typ, val)))
+				x.buf.WriteString(`"`)
+			}
+		}
+	}
+
+	if t == nil || len(t.elements) == 0 {
+		text := v
+		if obj != nil {
+			text = obj["#text"]
+		}
+		if text == nil {
+			x.buf.WriteString("/>\n")
+			return
+		}
+		x.buf.WriteString(">")
+		xml.EscapeText(&x.buf, []byte(xsdValue(t, text)))
+		x.buf.WriteString("</" + tag + ">\n")
+		return
+	}
+
+	x.buf.WriteString(">\n")
+	picked := map[int]*xsdElement{}
+	for _, child := range t.elements {
+		if child.choice == 0 {
+			continue
+		}
+		if _, ok := picked[child.choice]; !ok || x.rng.Intn(2) == 0 {
+			picked[child.choice] = child
+		}
+	}
+	for _, child := range t.elements {
+		if child.choice != 0 && picked[child.choice] != child {
+			continue
+		}
+		x.element(child, obj[child.name.Local], depth+1)
+	}
+	x.buf.WriteString(indent + "</" + tag + ">\n")
+}
+
+// complete reports whether a generated value has every required child. the
+// generator gives up on recursive types a few levels down, and optional
+// elements it gave up on are left out rather than written half empty
+func complete(el *xsdElement, v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok || el.typ == nil {
+		return true
+	}
+	for _, child := range el.typ.elements {
+		if child.min > 0 && child.choice == 0 && obj[child.name.Local] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// xsdValue formats a generated value as XSD lexical form
+func xsdValue(t *xsdType, v interface{}) string {
+	switch val := v.(type) {
+	case float64:
+		if t != nil && t.base == "decimal" {
+			return strconv.FormatFloat(val, 'f', 2, 64)
+		}
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case string:
+		if t != nil && t.base == "time" {
+			if ts, err := time.Parse(time.RFC3339, val); err == nil {
+				return ts.Format("15:04:05")
+			}
+		}
+		return val
+	}
+	return fmt.Sprint(v)
+}
+
+func (m *soapMock) writeEnvelope(w http.ResponseWriter, version string, status int, el *xsdElement, value interface{}, rng *rand.Rand) {
+	x := &soapWriter{prefixes: map[string]string{}, rng: rng}
+	x.element(el, value, 2)
+	m.send(w, version, status, x)
+}
+
+// writeFault sends a SOAP 1.1 or 1.2 fault and returns the HTTP status:
+// 500 for every 1.1 fault, 400 for 1.2 Sender faults
+func (m *soapMock) writeFault(w http.ResponseWriter, version string, op *soapOperation, f soapFault) int {
+	x := &soapWriter{prefixes: map[string]string{}}
+	var detail bytes.Buffer
+	if f.element != nil {
+		key := f.name
+		if op != nil {
+			key = op.name + "#fault#" + f.name
+		}
+		rng := seededRng(m.seed, key)
+		value := m.generate(f.element, rng)
+		if f.request != nil {
+			echoRequest(value, f.request)
+		}
+		x.rng = rng
+		x.element(f.element, value, 4)
+		detail.Write(x.buf.Bytes())
+		x.buf.Reset()
+	} else if len(f.errors) > 0 {
+		detail.WriteString("        <errors>\n")
+		for _, e := range f.errors {
+			detail.WriteString(`          <error field="`)
+			xml.EscapeText(&detail, []byte(e["field"]))
+			detail.WriteString(`">`)
+			xml.EscapeText(&detail, []byte(e["message"]))
+			detail.WriteString("</error>\n")
+		}
+		detail.WriteString("        </errors>\n")
+	}
+
+	status := http.StatusInternalServerError
+	x.buf.WriteString("    <soap:Fault>\n")
+	if version == "1.2" {
+		code := "soap:Receiver"
+		if f.sender {
+			code = "soap:Sender"
+			status = http.StatusBadRequest
+		}
+		x.buf.WriteString("      <soap:Code><soap:Value>" + code + "</soap:Value></soap:Code>\n")
+		x.buf.WriteString(`      <soap:Reason><soap:Text xml:lang="en">`)
+		xml.EscapeText(&x.buf, []byte(f.reason))
+		x.buf.WriteString("</soap:Text></soap:Reason>\n")
+		if detail.Len() > 0 {
+			x.buf.WriteString("      <soap:Detail>\n")
+			x.buf.Write(detail.Bytes())
+			x.buf.WriteString("      </soap:Detail>\n")
+		}
+	} else {
+		code := "soap:Server"
+		if f.sender {
+			code = "soap:Client"
+		}
+		x.buf.WriteString("      <faultcode>" + code + "</faultcode>\n")
+		x.buf.WriteString("      <faultstring>")
+		xml.EscapeText(&x.buf, []byte(f.reason))
+		x.buf.WriteString("</faultstring>\n")
+		if detail.Len() > 0 {
+			x.buf.WriteString("      <detail>\n")
+			x.buf.Write(detail.Bytes())
+			x.buf.WriteString("      </detail>\n")
+		}
+	}
+	x.buf.WriteString("    </soap:Fault>\n")
+	m.send(w, version, status, x)
+	return status
+}
+
+func (m *soapMock) send(w http.ResponseWriter, version string, status int, x *soapWriter) {
+	envNS, contentType := nsEnvelope11, "text/xml; charset=utf-8"
+	if version == "1.2" {
+		envNS, contentType = nsEnvelope12, "application/soap+xml; charset=utf-8"
+	}
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<soap:Envelope xmlns:soap="` + envNS + `"`)
+	for _, ns := range x.order {
+		b.WriteString(" xmlns:" + x.prefixes[ns] + `="`)
+		xml.EscapeText(&b, []byte(ns))
+		b.WriteString(`"`)
+	}
+	b.WriteString(">\n  <soap:Body>\n")
+	b.Write(x.buf.Bytes())
+	b.WriteString("  </soap:Body>\n</soap:Envelope>\n")
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(b.Bytes())
+}
+
+func (m *soapMock) routes() []routeInfo {
+	routes := make([]routeInfo, 0, len(m.operations))
+	for _, op := range m.operations {
+		label := "SOAP"
+		if op.version == "1.2" {
+			label = "SOAP12"
+		}
+		routes = append(routes, routeInfo{path: op.path + " " + op.name, methods: []string{label}})
+	}
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].path < routes[j].path })
+	return routes
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// specNotes are shown in the banner: what converting older specs changed or
+// couldn't carry over
+var specNotes []string
+
+// loadSpec loads an OpenAPI 3 document from a file or URL. Swagger 2.0 is
+// converted and 3.1 normalized on the way; the notes say what couldn't be
+// carried over
+func loadSpec(path string) (*openapi3.T, []string, error) {
+	doc, notes, _, err := loadSpecSource(path)
+	return doc, notes, err
+}
+
+// specSource is every document a spec load read: the root and whatever its
+// refs pulled in, by file path or URL. serve watches them all
+type specSource struct {
+	root  string
+	files map[string][]byte
+	is31  bool
+	found map[string]bool
+}
+
+var specClient = &http.Client{Timeout: 30 * time.Second}
+
+func isSpecURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// specLocation is where the loader resolves refs from. files are made
+// absolute so every document read has one name
+func specLocation(path string) (*url.URL, error) {
+	if isSpecURL(path) {
+		return url.Parse(path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{Path: filepath.ToSlash(abs)}, nil
+}
+
+func sourceKey(location *url.URL) string {
+	if location.Scheme == "" || location.Scheme == "file" {
+		return filepath.Clean(filepath.FromSlash(location.Path))
+	}
+	u := *location
+	u.Fragment = ""
+	return u.String()
+}
+
+// readSpecURI reads a document, never from a cache, so reloads see every change
+func readSpecURI(location *url.URL) ([]byte, error) {
+	return openapi3.ReadFromURIs(openapi3.ReadFromHTTP(specClient), openapi3.ReadFromFile)(nil, location)
+}
+
+func (src *specSource) loader() *openapi3.Loader {
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(_ *openapi3.Loader, location *url.URL) ([]byte, error) {
+		data, err := readSpecURI(location)
+		if err != nil {
+			return nil, err
+		}
+		src.files[sourceKey(location)] = data
+		if src.is31 {
+			return normalize31(data, src.found)
+		}
+		return data, nil
+	}
+	return loader
+}
+
+// loadSpecSource is loadSpec, also returning the documents it read
+func loadSpecSource(path string) (*openapi3.T, []string, *specSource, error) {
+	location, err := specLocation(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data, err := readSpecURI(location)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	src := &specSource{root: sourceKey(location), files: map[string][]byte{}, found: map[string]bool{}}
+	src.files[src.root] = data
+
+	var head struct {
+		Swagger string `yaml:"swagger"`
+	}
+	if yaml.Unmarshal(data, &head) != nil || head.Swagger == "" {
+		var notes []string
+		if isOpenAPI31(data) {
+			src.is31 = true
+			if data, err = normalize31(data, src.found); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		doc, err := src.loader().LoadFromDataWithPath(data, location)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if src.is31 {
+			notes = notes31(src.found)
+		}
+		return doc, notes, src, nil
+	}
+	if head.Swagger != "2.0" {
+		return nil, nil, nil, fmt.Errorf("unsupported swagger version %q", head.Swagger)
+	}
+	doc, notes, err := loadSwagger(src, location, data)
+	return doc, notes, src, err
+}
+
+// noteSpec records a spec's conversion notes for the banner. name tells
+// specs apart when serving several
+func noteSpec(name string, notes []string) {
+	for _, n := range notes {
+		if name != "" {
+			n = name + ": " + n
+		}
+		specNotes = append(specNotes, n)
+	}
+}
+
+func loadSwagger(src *specSource, location *url.URL, data []byte) (*openapi3.T, []string, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, nil, err
+	}
+	jsonData, err := json.Marshal(jsonValue(raw))
+	if err != nil {
+		return nil, nil, err
+	}
+	var doc2 openapi2.T
+	if err := json.Unmarshal(jsonData, &doc2); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse swagger 2.0: %w", err)
+	}
+
+	notes := []string{"converted from swagger 2.0"}
+	defaultFormConsumes(&doc2)
+
+	doc, err := openapi2conv.ToV3WithLoader(&doc2, src.loader(), location)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert swagger 2.0: %w", err)
+	}
+
+	// the converter only derives servers from host
+	if doc2.Host == "" && doc2.BasePath != "" && doc2.BasePath != "/" {
+		doc.AddServer(&openapi3.Server{URL: doc2.BasePath})
+	}
+	notes = append(notes, convertCollectionFormats(&doc2, doc)...)
+	return doc, notes, nil
+}
+
+// jsonValue turns YAML's non-string map keys, like response codes, into
+// strings so the document can go through encoding/json
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = jsonValue(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = jsonValue(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonValue(item)
+		}
+	}
+	return v
+}
+
+// defaultFormConsumes gives formData operations without consumes the media
+// type Swagger 2.0 implies, instead of the converter's */*
+func defaultFormConsumes(doc2 *openapi2.T) {
+	if len(doc2.Consumes) > 0 {
+		return
+	}
+	for _, item := range doc2.Paths {
+		for _, op := range item.Operations() {
+			if len(op.Consumes) > 0 {
+				continue
+			}
+			form, file := false, false
+			for _, p := range append(append(openapi2.Parameters{}, item.Parameters...), op.Parameters...) {
+				if p != nil && p.In == "formData" {
+					form = true
+					file = file || p.Type.Is("file")
+				}
+			}
+			switch {
+			case file:
+				op.Consumes = []string{"multipart/form-data"}
+			case form:
+				op.Consumes = []string{"application/x-www-form-urlencoded"}
+			}
+		}
+	}
+}
+
+// convertCollectionFormats maps collectionFormat to OpenAPI 3 styles, which
+// the converter drops. csv, the 2.0 default, would otherwise become an
+// exploded query array
+func convertCollectionFormats(doc2 *openapi2.T, doc *openapi3.T) []string {
+	var notes []string
+	apply := func(p2 *openapi2.Parameter, p3 *openapi3.Parameter, where string) {
+		if p2 == nil || p3 == nil || !p2.Type.Is("array") {
+			return
+		}
+		explode := false
+		switch p2.CollectionFormat {
+		case "", "csv":
+			if p3.In == openapi3.ParameterInQuery || p3.In == openapi3.ParameterInCookie {
+				p3.Style = openapi3.SerializationForm
+			}
+		case "multi":
+			p3.Style, explode = openapi3.SerializationForm, true
+		case "ssv":
+			p3.Style = openapi3.SerializationSpaceDelimited
+		case "pipes":
+			p3.Style = openapi3.SerializationPipeDelimited
+		default:
+			notes = append(notes, fmt.Sprintf("%s: collectionFormat %s has no OpenAPI 3 equivalent, using csv", where, p2.CollectionFormat))
+		}
+		p3.Explode = &explode
+	}
+
+	for name, p2 := range doc2.Parameters {
+		if ref := doc.Components.Parameters[name]; ref != nil {
+			apply(p2, ref.Value, "parameter "+name)
+		}
+	}
+	paths := make([]string, 0, len(doc2.Paths))
+	for path := range doc2.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item2, item3 := doc2.Paths[path], doc.Paths.Value(path)
+		if item3 == nil {
+			continue
+		}
+		for _, p2 := range item2.Parameters {
+			if p2 != nil && p2.Ref == "" {
+				apply(p2, findParameter(item3.Parameters, p2), path+" "+p2.Name)
+			}
+		}
+		ops := item2.Operations()
+		methods := make([]string, 0, len(ops))
+		for method := range ops {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			op3 := item3.GetOperation(method)
+			if op3 == nil {
+				continue
+			}
+			for _, p2 := range ops[method].Parameters {
+				if p2 != nil && p2.Ref == "" {
+					apply(p2, findParameter(op3.Parameters, p2), method+" "+path+" "+p2.Name)
+				}
+			}
+		}
+	}
+	return notes
+}
+
+func findParameter(params openapi3.Parameters, p2 *openapi2.Parameter) *openapi3.Parameter {
+	for _, ref := range params {
+		if ref != nil && ref.Value != nil && ref.Value.Name == p2.Name && ref.Value.In == p2.In {
+			return ref.Value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const splitRoot = `openapi: 3.0.3
+info: {title: split, version: "1"}
+paths:
+  /users/{id}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: './schemas/user.yaml'}
+`
+
+const userSchema = `type: object
+properties:
+  id: {type: string}
+  address: {$ref: './address.yaml'}
+`
+
+func addressSchema(field string) string {
+	return "type: object\nproperties:\n  " + field + ": {type: string}\n"
+}
+
+// specFiles serves documents from memory, standing in for a remote spec host
+type specFiles struct {
+	mu    sync.Mutex
+	files map[string]string
+}
+
+func (f *specFiles) set(name, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[name] = content
+}
+
+func (f *specFiles) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	content, ok := f.files[strings.TrimPrefix(r.URL.Path, "/")]
+	f.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write([]byte(content))
+}
+
+// addressFields are the properties the served spec's address schema has
+func addressFields(s *MockServer) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item := s.doc.Paths.Value("/users/{id}")
+	if item == nil || item.Get == nil {
+		return nil
+	}
+	schema := item.Get.Responses.Value("200").Value.Content.Get("application/json").Schema.Value
+	address := schema.Properties["address"]
+	if address == nil || address.Value == nil {
+		return nil
+	}
+	return sortedPropertyNames(address.Value)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRemoteSpecLoadAndReload(t *testing.T) {
+	files := &specFiles{files: map[string]string{
+		"api.yaml":             splitRoot,
+		"schemas/user.yaml":    userSchema,
+		"schemas/address.yaml": addressSchema("city"),
+	}}
+	srv := httptest.NewServer(files)
+	defer srv.Close()
+
+	defer func(d time.Duration) { remotePollInterval = d }(remotePollInterval)
+	remotePollInterval = 50 * time.Millisecond
+
+	m := &specMount{file: srv.URL + "/api.yaml"}
+	s, err := loadMockServer(m, "", nil)
+	if err != nil {
+		t.Fatalf("loading %s: %v", m.file, err)
+	}
+	if got := addressFields(s); len(got) != 1 || got[0] != "city" {
+		t.Fatalf("address fields %v, want [city]", got)
+	}
+	if len(m.source.files) != 3 {
+		t.Errorf("read %d documents, want 3: %v", len(m.source.files), m.source.files)
+	}
+
+	stop := s.watch(m.file, m.source)
+	defer stop()
+	files.set("schemas/address.yaml", addressSchema("country"))
+	waitFor(t, "the remote ref to reload", func() bool {
+		got := addressFields(s)
+		return len(got) == 1 && got[0] == "country"
+	})
+}
+
+func TestRefFileEditReloads(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("api.yaml", splitRoot)
+	write("schemas/user.yaml", userSchema)
+	write("schemas/address.yaml", addressSchema("city"))
+
+	m := &specMount{file: filepath.Join(dir, "api.yaml")}
+	s, err := loadMockServer(m, "", nil)
+	if err != nil {
+		t.Fatalf("loading %s: %v", m.file, err)
+	}
+	stop := s.watch(m.file, m.source)
+	defer stop()
+
+	// an in-place save of a file two refs deep
+	write("schemas/address.yaml", addressSchema("zip"))
+	waitFor(t, "the edited ref to reload", func() bool {
+		got := addressFields(s)
+		return len(got) == 1 && got[0] == "zip"
+	})
+
+	// a save that renames a new file over the old one, like most editors
+	write("schemas/address.yaml.tmp", addressSchema("street"))
+	if err := os.Rename(filepath.Join(dir, "schemas/address.yaml.tmp"), filepath.Join(dir, "schemas/address.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the renamed ref to reload", func() bool {
+		got := addressFields(s)
+		return len(got) == 1 && got[0] == "street"
+	})
+}
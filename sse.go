@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// sseInterval is how often generated events are sent (--sse-interval)
+var sseInterval = time.Second
+
+// eventStream is everything needed to serve one text/event-stream response,
+// resolved up front so the stream doesn't hold the server's spec lock
+type eventStream struct {
+	schema    *openapi3.SchemaRef
+	event     string
+	interval  time.Duration
+	limit     int
+	seed      int64
+	path      string
+	resource  string // store changes of this resource are streamed; "" means all
+	store     *Store
+	done      <-chan struct{}
+	lastID    int
+	hasLastID bool
+}
+
+// newEventStream reads the stream settings: the response schema for event data,
+// x-portblock-event / x-portblock-interval on the media type, and the
+// Prefer: interval=, events= hints
+func (s *MockServer) newEventStream(r *http.Request, op *openapi3.Operation) *eventStream {
+	es := &eventStream{
+		interval: sseInterval,
+		seed:     s.seed,
+		path:     r.URL.Path,
+		store:    s.store,
+		done:     s.done,
+	}
+
+	if _, resp := successResponse(op); resp != nil {
+		if media := resp.Content.Get("text/event-stream"); media != nil {
+			es.schema = media.Schema
+			if name, ok := media.Extensions["x-portblock-event"].(string); ok {
+				es.event = name
+			}
+			if v, ok := media.Extensions["x-portblock-interval"].(string); ok {
+				if d, err := time.ParseDuration(v); err == nil && d > 0 {
+					es.interval = d
+				}
+			}
+		}
+	}
+	if v := parsePreference(r, "interval"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			es.interval = d
+		}
+	}
+	if v := parsePreference(r, "events"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			es.limit = n
+		}
+	}
+
+	// a stream under a CRUD collection (/users/stream) follows that collection;
+	// standalone ones (/events) follow everything
+	if resource := extractResource(r.URL.Path); s.isCollection(resource) {
+		es.resource = resource
+	}
+
+	if id, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil && id >= 0 {
+		es.lastID, es.hasLastID = id, true
+	}
+	return es
+}
+
+// isCollection reports whether the spec has CRUD routes for a resource
+func (s *MockServer) isCollection(resource string) bool {
+	for pattern, item := range s.doc.Paths.Map() {
+		if extractResource(pattern) != resource {
+			continue
+		}
+		if item.Post != nil || strings.Contains(pattern, "{") {
+			return true
+		}
+	}
+	return false
+}
+
+// serve streams events until the client goes away, the event limit is hit or
+// the server shuts down. generated events are seeded by id, so a client
+// resuming with Last-Event-ID gets the same events it would have seen
+func (es *eventStream) serve(w http.ResponseWriter, r *http.Request) int {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeResponse(w, "application/json", 500, map[string]string{"error": "streaming unsupported"})
+		return 500
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+	flusher.Flush()
+
+	changes, unsubscribe := es.store.Subscribe()
+	defer unsubscribe()
+
+	next := 1
+	if es.hasLastID {
+		next = es.lastID + 1
+	}
+	sent := 0
+	send := func(event string, data interface{}) {
+		writeEvent(w, next, event, data)
+		flusher.Flush()
+		next++
+		sent++
+	}
+
+	var tick <-chan time.Time
+	if es.schema != nil {
+		ticker := time.NewTicker(es.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+		send(es.generate(next))
+	}
+
+	for es.limit == 0 || sent < es.limit {
+		select {
+		case <-r.Context().Done():
+			return 200
+		case <-es.done:
+			return 200
+		case <-tick:
+			send(es.generate(next))
+		case change := <-changes:
+			if es.resource == "" || change.Resource == es.resource {
+				send(change.Name(), change.Data)
+			}
+		}
+	}
+	return 200
+}
+
+// generate builds event id's data. oneOf/anyOf schemas pick a variant per
+// event, named after its component
+func (es *eventStream) generate(id int) (string, interface{}) {
+	rng := seededRng(es.seed, es.path+"#event"+strconv.Itoa(id))
+	schema := es.schema
+	if v := schema.Value; v != nil {
+		variants := v.OneOf
+		if len(variants) == 0 {
+			variants = v.AnyOf
+		}
+		if len(variants) > 0 {
+			schema = variants[rng.Intn(len(variants))]
+		}
+	}
+	event := es.event
+	if event == "" {
+		event = schemaName(schema)
+	}
+	return event, generateFromSchema(schema, rng, 0)
+}
+
+// writeEvent writes one SSE frame. strings are sent as-is, everything else as JSON
+func writeEvent(w http.ResponseWriter, id int, event string, data interface{}) {
+	fmt.Fprintf(w, "id: %d\n", id)
+	if event != "" {
+		fmt.Fprintf(w, "event: %s\n", event)
+	}
+	text, ok := data.(string)
+	if !ok {
+		b, _ := json.Marshal(data)
+		text = string(b)
+	}
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
@@ -14,7 +14,7 @@ var strictMode bool
 
 // strictValidateSpec rejects specs with validation warnings in strict mode
 func strictValidateSpec(doc *openapi3.T) error {
-	err := doc.Validate(context.Background())
+	err := doc.Validate(context.Background(), specValidationOptions()...)
 	if err != nil {
 		if strictMode {
 			return fmt.Errorf("strict mode: spec validation failed: %w", err)
@@ -24,6 +24,14 @@ func strictValidateSpec(doc *openapi3.T) error {
 	return nil
 }
 
+// specValidationOptions lets through JSON Schema keywords portblock understands
+// but kin-openapi would otherwise report as unknown sibling fields
+func specValidationOptions() []openapi3.ValidationOption {
+	return []openapi3.ValidationOption{
+		openapi3.AllowExtraSiblingFields(append([]string{"propertyNames"}, keywords31...)...),
+	}
+}
+
 // validateResponseAgainstSchema validates generated response data against schema constraints
 // returns a list of warnings (does not block response in non-strict mode)
 func validateResponseAgainstSchema(schema *openapi3.SchemaRef, data interface{}, path string) []string {
@@ -34,14 +42,20 @@ func validateResponseAgainstSchema(schema *openapi3.SchemaRef, data interface{},
 	var warnings []string
 
 	types := s.Type.Slice()
-	if len(types) == 0 && len(s.Properties) > 0 {
+	if len(types) == 0 && (len(s.Properties) > 0 || s.AdditionalProperties.Schema != nil) {
 		types = []string{"object"}
 	}
 	if len(types) == 0 {
 		return nil
 	}
 
-	switch types[0] {
+	// with several types, the value is checked as whichever it is
+	typ := matchType(types, data)
+	if typ == "" {
+		return []string{fmt.Sprintf("%s: expected %s, got %T", path, describeTypes(types), data)}
+	}
+
+	switch typ {
 	case "object":
 		m, ok := data.(map[string]interface{})
 		if !ok {
@@ -60,6 +74,7 @@ func validateResponseAgainstSchema(schema *openapi3.SchemaRef, data interface{},
 				warnings = append(warnings, sub...)
 			}
 		}
+		warnings = append(warnings, validateAdditionalProperties(s, m, path)...)
 
 	case "array":
 		arr, ok := data.([]interface{})
@@ -72,7 +87,16 @@ func validateResponseAgainstSchema(schema *openapi3.SchemaRef, data interface{},
 		if s.MaxItems != nil && uint64(len(arr)) > *s.MaxItems {
 			warnings = append(warnings, fmt.Sprintf("%s: array has %d items, maximum is %d", path, len(arr), *s.MaxItems))
 		}
-		if s.Items != nil {
+		if prefix, rest, ok := tupleItems(s); ok {
+			for i, item := range arr {
+				itemSchema := rest
+				if i < len(prefix) {
+					itemSchema = prefix[i]
+				}
+				sub := validateResponseAgainstSchema(itemSchema, item, fmt.Sprintf("%s[%d]", path, i))
+				warnings = append(warnings, sub...)
+			}
+		} else if s.Items != nil {
 			for i, item := range arr {
 				sub := validateResponseAgainstSchema(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
 				warnings = append(warnings, sub...)
@@ -137,6 +161,44 @@ func validateResponseAgainstSchema(schema *openapi3.SchemaRef, data interface{},
 	return warnings
 }
 
+// validateAdditionalProperties checks map-typed objects: extra keys against
+// additionalProperties, key names against propertyNames, and property counts
+func validateAdditionalProperties(s *openapi3.Schema, m map[string]interface{}, path string) []string {
+	var warnings []string
+
+	if s.MinProps > 0 && uint64(len(m)) < s.MinProps {
+		warnings = append(warnings, fmt.Sprintf("%s: object has %d properties, minimum is %d", path, len(m), s.MinProps))
+	}
+	if s.MaxProps != nil && uint64(len(m)) > *s.MaxProps {
+		warnings = append(warnings, fmt.Sprintf("%s: object has %d properties, maximum is %d", path, len(m), *s.MaxProps))
+	}
+
+	keyRe, keyEnum := propertyNamesRule(s)
+
+	for key, val := range m {
+		// propertyNames applies to every key, declared ones included
+		if keyRe != nil && !keyRe.MatchString(key) {
+			warnings = append(warnings, fmt.Sprintf("%s: property name '%s' doesn't match pattern '%s'", path, truncate(key, 30), keyRe))
+		}
+		if len(keyEnum) > 0 && !inKeyEnum(keyEnum, key) {
+			warnings = append(warnings, fmt.Sprintf("%s: property name '%s' not in enum %v", path, truncate(key, 30), keyEnum))
+		}
+		if _, declared := s.Properties[key]; declared {
+			continue
+		}
+		if s.AdditionalProperties.Has != nil && !*s.AdditionalProperties.Has {
+			warnings = append(warnings, fmt.Sprintf("%s: unexpected property '%s' (additionalProperties is false)", path, key))
+			continue
+		}
+		if s.AdditionalProperties.Schema != nil {
+			sub := validateResponseAgainstSchema(s.AdditionalProperties.Schema, val, path+"."+key)
+			warnings = append(warnings, sub...)
+		}
+	}
+
+	return warnings
+}
+
 func toFloat64(v interface{}) (float64, bool) {
 	switch n := v.(type) {
 	case float64:
@@ -185,8 +247,7 @@ func strictValidateRequestBody(schema *openapi3.SchemaRef, body map[string]inter
 		if prop.Value == nil {
 			continue
 		}
-		propTypes := prop.Value.Type.Slice()
-		if len(propTypes) > 0 && propTypes[0] == "object" {
+		if prop.Value.Type.Includes("object") {
 			if nested, ok := body[name].(map[string]interface{}); ok {
 				sub := strictValidateRequestBody(prop, nested)
 				for _, e := range sub {
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const labelsSpec = `openapi: 3.0.3
+info: {title: labels, version: "1"}
+paths:
+  /labels:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Labels'}
+components:
+  schemas:
+    Labels:
+      type: object
+      propertyNames: {pattern: '^[a-z]+(-[a-z]+)*$'}
+      properties:
+        team: {type: string}
+        Owner: {type: string}
+        cost_center: {type: string}
+      required: [team]
+      additionalProperties: {type: string}
+      minProperties: 3
+    Regions:
+      type: object
+      propertyNames: {enum: [eu, us, apac]}
+      properties:
+        eu: {type: integer}
+        mars: {type: integer}
+      additionalProperties: {type: integer}
+`
+
+func loadTestSpec(t *testing.T, content string) *MockServer {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "api.yaml")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := loadMockServer(&specMount{file: file}, "", nil)
+	if err != nil {
+		t.Fatalf("loading %s: %v", file, err)
+	}
+	return s
+}
+
+func TestPropertyNamesGeneration(t *testing.T) {
+	s := loadTestSpec(t, labelsSpec)
+	labels := s.doc.Components.Schemas["Labels"]
+	regions := s.doc.Components.Schemas["Regions"]
+	keyRe := regexp.MustCompile(`^[a-z]+(-[a-z]+)*$`)
+
+	for i := int64(0); i < 50; i++ {
+		obj, ok := generateFromSchema(labels, seededRng(i, "/labels"), 0).(map[string]interface{})
+		if !ok {
+			t.Fatalf("seed %d: generated %T, want an object", i, obj)
+		}
+		if _, ok := obj["team"]; !ok {
+			t.Errorf("seed %d: required property team is missing: %v", i, obj)
+		}
+		if len(obj) < 3 {
+			t.Errorf("seed %d: %d properties, minProperties is 3", i, len(obj))
+		}
+		for key := range obj {
+			if !keyRe.MatchString(key) {
+				t.Errorf("seed %d: key %q doesn't match propertyNames", i, key)
+			}
+		}
+		if warnings := validateResponseAgainstSchema(labels, obj, "labels"); len(warnings) > 0 {
+			t.Errorf("seed %d: generated labels don't validate: %s", i, strings.Join(warnings, "; "))
+		}
+
+		obj, _ = generateFromSchema(regions, seededRng(i, "/regions"), 0).(map[string]interface{})
+		for key := range obj {
+			if key != "eu" && key != "us" && key != "apac" {
+				t.Errorf("seed %d: region key %q isn't in the propertyNames enum", i, key)
+			}
+		}
+	}
+}
+
+func TestPropertyNamesValidation(t *testing.T) {
+	s := loadTestSpec(t, labelsSpec)
+	labels := s.doc.Components.Schemas["Labels"]
+	regions := s.doc.Components.Schemas["Regions"]
+
+	tests := []struct {
+		name  string
+		ref   string
+		value map[string]interface{}
+		want  []string // substrings of the expected warnings, in any order
+	}{
+		{"valid", "labels", map[string]interface{}{"team": "a", "env": "b", "cost-center": "c"}, nil},
+		{"declared key breaks the pattern", "labels", map[string]interface{}{"team": "a", "Owner": "b", "env": "c"}, []string{"'Owner' doesn't match pattern"}},
+		{"extra key breaks the pattern", "labels", map[string]interface{}{"team": "a", "env": "b", "Bad_Key": "c"}, []string{"'Bad_Key' doesn't match pattern"}},
+		{"too few properties", "labels", map[string]interface{}{"team": "a"}, []string{"minimum is 3"}},
+		{"declared key outside the enum", "regions", map[string]interface{}{"eu": 1, "mars": 2}, []string{"'mars' not in enum"}},
+		{"extra key in the enum", "regions", map[string]interface{}{"us": 1}, nil},
+	}
+	for _, tt := range tests {
+		schema := labels
+		if tt.ref == "regions" {
+			schema = regions
+		}
+		warnings := validateResponseAgainstSchema(schema, tt.value, tt.ref)
+		if len(warnings) != len(tt.want) {
+			t.Errorf("%s: warnings %q, want %d", tt.name, warnings, len(tt.want))
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(strings.Join(warnings, "\n"), want) {
+				t.Errorf("%s: warnings %q, want one containing %q", tt.name, warnings, want)
+			}
+		}
+	}
+}
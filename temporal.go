@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// defaultNow is the clock without --now. it's fixed rather than the wall
+// clock, so the same --seed gives the same dates on any day
+var defaultNow = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// mockNow is the clock generated dates are relative to (--now / config
+// "now"). zero follows the wall clock
+var mockNow = defaultNow
+
+// applyNow sets the clock from --now: a timestamp, a date, or "now" for the
+// wall clock. empty keeps the default
+func applyNow(s string) error {
+	switch s {
+	case "":
+		mockNow = defaultNow
+	case "now":
+		mockNow = time.Time{}
+	default:
+		t, err := parseNow(s)
+		if err != nil {
+			return err
+		}
+		mockNow = t
+	}
+	return nil
+}
+
+// parseNow accepts RFC3339 timestamps or plain dates
+func parseNow(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --now %q: use RFC3339 (2026-01-02T15:04:05Z), a date (2026-01-02) or now", s)
+}
+
+func currentNow() time.Time {
+	if mockNow.IsZero() {
+		return time.Now().UTC().Truncate(time.Second)
+	}
+	return mockNow
+}
+
+// timeline holds one object's timestamps, drawn so that
+// created <= published <= updated <= deleted <= now < expires and starts < ends
+type timeline struct {
+	now       time.Time
+	created   time.Time
+	published time.Time
+	updated   time.Time
+	deleted   time.Time
+	starts    time.Time
+	ends      time.Time
+	expires   time.Time
+	birth     time.Time
+}
+
+func newTimeline(rng *rand.Rand) timeline {
+	now := currentNow()
+	t := timeline{now: now}
+
+	t.created = now.Add(-randDuration(rng, time.Hour, 730*24*time.Hour))
+	t.updated = t.created.Add(randDuration(rng, 0, now.Sub(t.created)))
+	t.published = t.created.Add(randDuration(rng, 0, t.updated.Sub(t.created)))
+	t.deleted = t.updated.Add(randDuration(rng, 0, now.Sub(t.updated)))
+	t.starts = now.Add(randDuration(rng, -30*24*time.Hour, 60*24*time.Hour))
+	t.ends = t.starts.Add(randDuration(rng, time.Hour, 30*24*time.Hour))
+	t.expires = now.Add(randDuration(rng, 24*time.Hour, 365*24*time.Hour))
+
+	years := 18 + rng.Intn(62)
+	t.birth = now.AddDate(-years, 0, -rng.Intn(365))
+
+	return t
+}
+
+// randDuration returns a random duration in [min, max)
+func randDuration(rng *rand.Rand, min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(rng.Int63n(int64(max-min)))
+}
+
+var camelBoundaryRe = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// nameTokens splits snake_case, kebab-case and camelCase names into lowercase words
+func nameTokens(name string) []string {
+	name = camelBoundaryRe.ReplaceAllString(name, "${1}_${2}")
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+}
+
+var temporalKinds = map[string]string{
+	"birth": "birth", "birthday": "birth", "birthdate": "birth", "dob": "birth", "born": "birth",
+	"created": "created", "creation": "created", "inserted": "created", "registered": "created", "joined": "created", "signup": "created",
+	"published": "published", "posted": "published",
+	"updated": "updated", "modified": "updated", "edited": "updated", "changed": "updated",
+	"deleted": "deleted", "removed": "deleted", "archived": "deleted", "cancelled": "deleted", "canceled": "deleted",
+	"expires": "expires", "expiry": "expires", "expiration": "expires", "expire": "expires", "until": "expires",
+	"start": "starts", "starts": "starts", "started": "starts", "begin": "starts", "begins": "starts", "from": "starts",
+	"end": "ends", "ends": "ends", "ended": "ends", "finish": "ends", "finished": "ends",
+}
+
+// temporalKind infers what a timestamp means from its property name
+func temporalKind(propName string) string {
+	for _, tok := range nameTokens(propName) {
+		if kind, ok := temporalKinds[tok]; ok {
+			return kind
+		}
+	}
+	return ""
+}
+
+// isTemporalField reports whether a property holds a date or time
+func isTemporalField(propName string, schema *openapi3.Schema, typ string) bool {
+	switch typ {
+	case "string":
+		if schema.Format == "date-time" || schema.Format == "date" {
+			return true
+		}
+		if schema.Format != "" || len(schema.Pattern) > 0 {
+			return false
+		}
+	case "integer":
+		if schema.Format != "" && schema.Format != "int64" {
+			return false
+		}
+	default:
+		return false
+	}
+	tokens := nameTokens(propName)
+	// integer timestamps can say they're in milliseconds: created_at_ms
+	if last := len(tokens) - 1; typ == "integer" && last > 0 && (tokens[last] == "ms" || tokens[last] == "millis") {
+		tokens = tokens[:last]
+	}
+	if len(tokens) < 2 && temporalKind(propName) != "birth" {
+		return false
+	}
+	switch tokens[len(tokens)-1] {
+	case "at", "date", "time", "timestamp", "ts", "on", "dob", "birthday", "birthdate":
+		return true
+	}
+	return false
+}
+
+// value renders the timestamp for a property in the schema's representation
+func (t timeline) value(propName string, schema *openapi3.Schema, typ string, rng *rand.Rand) interface{} {
+	var ts time.Time
+	switch temporalKind(propName) {
+	case "birth":
+		ts = t.birth
+	case "created":
+		ts = t.created
+	case "published":
+		ts = t.published
+	case "updated":
+		ts = t.updated
+	case "deleted":
+		ts = t.deleted
+	case "expires":
+		ts = t.expires
+	case "starts":
+		ts = t.starts
+	case "ends":
+		ts = t.ends
+	default:
+		ts = randomPastTime(rng)
+	}
+
+	if typ == "integer" {
+		for _, tok := range nameTokens(propName) {
+			if tok == "ms" || tok == "millis" {
+				return ts.UnixMilli()
+			}
+		}
+		return ts.Unix()
+	}
+	if schema.Format == "date" || (schema.Format == "" && temporalKind(propName) == "birth") {
+		return ts.Format("2006-01-02")
+	}
+	return ts.Format(time.RFC3339)
+}
+
+// randomPastTime returns a time within the year before now
+func randomPastTime(rng *rand.Rand) time.Time {
+	return currentNow().Add(-randDuration(rng, 0, 365*24*time.Hour))
+}
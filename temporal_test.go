@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyNow(t *testing.T) {
+	defer func(now time.Time) { mockNow = now }(mockNow)
+
+	tests := []struct {
+		flag string
+		want time.Time
+		wall bool
+	}{
+		{"", defaultNow, false},
+		{"2027-06-01", time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2027-06-01T12:30:00+02:00", time.Date(2027, 6, 1, 10, 30, 0, 0, time.UTC), false},
+		{"now", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		if err := applyNow(tt.flag); err != nil {
+			t.Fatalf("%q: %v", tt.flag, err)
+		}
+		if tt.wall {
+			if d := time.Since(currentNow()); d < 0 || d > time.Minute {
+				t.Errorf("%q: clock %v isn't the wall clock", tt.flag, currentNow())
+			}
+			continue
+		}
+		if got := currentNow(); !got.Equal(tt.want) {
+			t.Errorf("%q: clock %v, want %v", tt.flag, got, tt.want)
+		}
+	}
+	if err := applyNow("yesterday"); err == nil {
+		t.Error("expected an error for an unparseable --now")
+	}
+}
+
+// without --now, a seed fixes the dates as well as everything else
+func TestSeededTimelineIsStable(t *testing.T) {
+	defer func(now time.Time) { mockNow = now }(mockNow)
+	applyNow("")
+	a := newTimeline(seededRng(42, "/users"))
+	b := newTimeline(seededRng(42, "/users"))
+	if !a.now.Equal(defaultNow) {
+		t.Errorf("timeline is relative to %v, want %v", a.now, defaultNow)
+	}
+	if a != b {
+		t.Errorf("same seed gave different timelines:\n%+v\n%+v", a, b)
+	}
+}
@@ -15,7 +15,6 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
-	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/routers/gorillamux"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -149,11 +148,11 @@ func runTest(cmd *cobra.Command, args []string) error {
 }
 
 func startInternalMock(specFile string) (string, func(), error) {
-	loader := openapi3.NewLoader()
-	doc, err := loader.LoadFromFile(specFile)
+	doc, notes, src, err := loadSpecSource(specFile)
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to load spec: %w", err)
 	}
+	noteSpec("", notes)
 
 	router, err := gorillamux.NewRouter(doc)
 	if err != nil {
@@ -162,11 +161,13 @@ func startInternalMock(specFile string) (string, func(), error) {
 
 	mockSeed := time.Now().UnixNano()
 	server := &MockServer{
-		doc:    doc,
-		store:  NewStore(),
-		seed:   mockSeed,
-		router: router,
-		noAuth: true, // disable auth for testing
+		doc:       doc,
+		store:     NewStore(),
+		seed:      mockSeed,
+		router:    router,
+		routes:    newRouteTable(doc),
+		noAuth:    true, // disable auth for testing
+		propOrder: buildPropertyOrder(doc, src),
 	}
 
 	mux := http.NewServeMux()
@@ -432,6 +433,7 @@ func renderTestBanner(specFile, testFile, target string, testCount int) string {
 	b.WriteString(styleLabel.Render("tests") + styleValue.Render(testFile) + "\n")
 	b.WriteString(styleLabel.Render("target") + styleURL.Render(target) + "\n")
 	b.WriteString(styleLabel.Render("count") + styleValue.Render(fmt.Sprintf("%d tests", testCount)) + "\n")
+	renderSpecNotes(&b)
 	return styleBanner.Render(b.String())
 }
 
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// TLSConfig is the tls section of .portblock.yaml
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	Cert     string `yaml:"cert,omitempty" json:"cert,omitempty"`
+	Key      string `yaml:"key,omitempty" json:"key,omitempty"`
+	ClientCA string `yaml:"client-ca,omitempty" json:"client-ca,omitempty"`
+	MTLS     bool   `yaml:"mtls,omitempty" json:"mtls,omitempty"`
+}
+
+// tlsOpts are the active TLS settings; tlsCAPath is set when the local CA
+// signed the server certificate, so the banner can say what to trust
+var (
+	tlsOpts   TLSConfig
+	tlsCAPath string
+)
+
+func addTLSFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&tlsOpts.Enabled, "tls", false, "serve HTTPS and HTTP/2, with a certificate from a local CA unless --tls-cert is given")
+	cmd.Flags().StringVar(&tlsOpts.Cert, "tls-cert", "", "TLS certificate file (PEM), implies --tls")
+	cmd.Flags().StringVar(&tlsOpts.Key, "tls-key", "", "TLS private key file (PEM)")
+	cmd.Flags().BoolVar(&tlsOpts.MTLS, "mtls", false, "require client certificates, implies --tls")
+	cmd.Flags().StringVar(&tlsOpts.ClientCA, "tls-client-ca", "", "CA file client certificates must chain to (default: the local CA)")
+}
+
+func (c TLSConfig) active() bool {
+	return c.Enabled || c.Cert != "" || c.MTLS
+}
+
+func scheme() string {
+	if tlsOpts.active() {
+		return "https"
+	}
+	return "http"
+}
+
+// configureTLS sets up srv for HTTPS and HTTP/2
+func configureTLS(srv *http.Server) error {
+	if !tlsOpts.active() {
+		return nil
+	}
+	cfg, err := buildTLSConfig()
+	if err != nil {
+		return err
+	}
+	srv.TLSConfig = cfg
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	srv.Protocols = protocols
+	return nil
+}
+
+// buildTLSConfig loads --tls-cert or, without it, issues a certificate from a
+// local CA kept in ~/.portblock/tls, so clients only have to trust ca.pem once
+func buildTLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	var ca *localCA
+	if tlsOpts.Cert != "" || tlsOpts.Key != "" {
+		if tlsOpts.Cert == "" || tlsOpts.Key == "" {
+			return nil, fmt.Errorf("--tls-cert and --tls-key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(tlsOpts.Cert, tlsOpts.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	} else {
+		var err error
+		if ca, err = loadLocalCA(); err != nil {
+			return nil, err
+		}
+		cert, err := ca.issue(serverTemplate())
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+		tlsCAPath = ca.path
+	}
+
+	// client certificates are only asked for with --mtls or --tls-client-ca, so
+	// plain --tls handshakes don't send browsers a certificate request
+	switch {
+	case tlsOpts.ClientCA != "":
+		data, err := os.ReadFile(tlsOpts.ClientCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in %s", tlsOpts.ClientCA)
+		}
+		cfg.ClientCAs = pool
+	case tlsOpts.MTLS:
+		if ca == nil {
+			var err error
+			if ca, err = loadLocalCA(); err != nil {
+				return nil, err
+			}
+		}
+		if err := ca.writeClientCert(); err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = x509.NewCertPool()
+		cfg.ClientCAs.AddCert(ca.cert)
+	}
+	if cfg.ClientCAs != nil {
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if tlsOpts.MTLS {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return cfg, nil
+}
+
+// serveHTTP runs srv over TLS when configureTLS set it up, plain HTTP otherwise
+func serveHTTP(srv *http.Server) error {
+	var err error
+	if srv.TLSConfig != nil {
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// localCA is portblock's own certificate authority
+type localCA struct {
+	dir  string
+	path string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func loadLocalCA() (*localCA, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(home, ".portblock", "tls")
+	ca := &localCA{dir: dir, path: filepath.Join(dir, "ca.pem")}
+	keyPath := filepath.Join(dir, "ca-key.pem")
+
+	if pair, err := tls.LoadX509KeyPair(ca.path, keyPath); err == nil {
+		cert, err := x509.ParseCertificate(pair.Certificate[0])
+		key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+		if err == nil && ok && time.Now().Before(cert.NotAfter) {
+			ca.cert, ca.key = cert, key
+			return ca, nil
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber(),
+		Subject:               pkix.Name{CommonName: "portblock local CA", Organization: []string{"portblock"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := writePEM(ca.path, "CERTIFICATE", der, 0644); err != nil {
+		return nil, err
+	}
+	if err := writeKey(keyPath, key); err != nil {
+		return nil, err
+	}
+	ca.cert, _ = x509.ParseCertificate(der)
+	ca.key = key
+	return ca, nil
+}
+
+// serverTemplate is a leaf for localhost and this machine. it's issued fresh
+// on every start, only the CA is kept
+func serverTemplate() *x509.Certificate {
+	t := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost", Organization: []string{"portblock"}},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if host, err := os.Hostname(); err == nil && host != "localhost" {
+		t.DNSNames = append(t.DNSNames, host)
+	}
+	return t
+}
+
+func (ca *localCA) issue(template *x509.Certificate) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template.SerialNumber = serialNumber()
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().AddDate(1, 0, 0)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key}, nil
+}
+
+// writeClientCert leaves a client certificate next to the CA for mTLS
+// clients (curl --cert client.pem --key client-key.pem), unless a current one
+// is already there
+func (ca *localCA) writeClientCert() error {
+	certPath := filepath.Join(ca.dir, "client.pem")
+	keyPath := filepath.Join(ca.dir, "client-key.pem")
+	if pair, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil {
+		if cert, err := x509.ParseCertificate(pair.Certificate[0]); err == nil &&
+			time.Now().Before(cert.NotAfter) && cert.CheckSignatureFrom(ca.cert) == nil {
+			return nil
+		}
+	}
+	cert, err := ca.issue(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: "portblock client", Organization: []string{"portblock"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return err
+	}
+	if err := writePEM(certPath, "CERTIFICATE", cert.Certificate[0], 0644); err != nil {
+		return err
+	}
+	return writeKey(keyPath, cert.PrivateKey.(*ecdsa.PrivateKey))
+}
+
+func serialNumber() *big.Int {
+	n, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	return n
+}
+
+func writeKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return writePEM(path, "EC PRIVATE KEY", der, 0600)
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), perm)
+}
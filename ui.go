@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"google.golang.org/grpc/codes"
 )
 
 // color palette — dark theme friendly, tasteful
@@ -63,6 +64,16 @@ var (
 		"PUT":    lipgloss.NewStyle().Foreground(colorBlue).Bold(true).Width(7),
 		"PATCH":  lipgloss.NewStyle().Foreground(colorOrange).Bold(true).Width(7),
 		"DELETE": lipgloss.NewStyle().Foreground(colorRed).Bold(true).Width(7),
+		"WS":     lipgloss.NewStyle().Foreground(colorCyan).Bold(true).Width(7),
+		"QUERY":  lipgloss.NewStyle().Foreground(colorGreen).Bold(true).Width(7),
+		"MUTATE": lipgloss.NewStyle().Foreground(colorYellow).Bold(true).Width(7),
+		"GRPC":   lipgloss.NewStyle().Foreground(colorPrimary).Bold(true).Width(7),
+		"UNARY":  lipgloss.NewStyle().Foreground(colorGreen).Bold(true).Width(7),
+		"STREAM": lipgloss.NewStyle().Foreground(colorCyan).Bold(true).Width(7),
+		"CLIENT": lipgloss.NewStyle().Foreground(colorBlue).Bold(true).Width(7),
+		"BIDI":   lipgloss.NewStyle().Foreground(colorOrange).Bold(true).Width(7),
+		"SOAP":   lipgloss.NewStyle().Foreground(colorBlue).Bold(true).Width(7),
+		"SOAP12": lipgloss.NewStyle().Foreground(colorBlue).Bold(true).Width(7),
 	}
 
 	stylePath = lipgloss.NewStyle().
@@ -116,7 +127,7 @@ func renderBanner(mode, specFile string, portNum int, seedVal int64, delayVal ti
 	// config
 	b.WriteString(styleLabel.Render("spec") + styleValue.Render(specFile) + "\n")
 	b.WriteString(styleLabel.Render("port") + styleValue.Render(fmt.Sprintf("%d", portNum)) + "\n")
-	if mode == "serve" {
+	if seedVal != 0 {
 		b.WriteString(styleLabel.Render("seed") + styleValue.Render(fmt.Sprintf("%d", seedVal)) + "\n")
 	}
 	if delayVal > 0 {
@@ -128,6 +139,8 @@ func renderBanner(mode, specFile string, portNum int, seedVal int64, delayVal ti
 	if noAuthMode {
 		b.WriteString(styleLabel.Render("auth") + lipgloss.NewStyle().Foreground(colorDim).Render("disabled") + "\n")
 	}
+	renderTLS(&b)
+	renderSpecNotes(&b)
 
 	return styleBanner.Render(b.String())
 }
@@ -145,6 +158,8 @@ func renderProxyBanner(specFile, target string, portNum int, recordFile string)
 	if recordFile != "" {
 		b.WriteString(styleLabel.Render("record") + styleValue.Render(recordFile) + "\n")
 	}
+	renderTLS(&b)
+	renderSpecNotes(&b)
 
 	return styleBanner.Render(b.String())
 }
@@ -159,10 +174,39 @@ func renderReplayBanner(recordFile string, portNum, entries int) string {
 	b.WriteString(styleLabel.Render("file") + styleValue.Render(recordFile) + "\n")
 	b.WriteString(styleLabel.Render("port") + styleValue.Render(fmt.Sprintf("%d", portNum)) + "\n")
 	b.WriteString(styleLabel.Render("entries") + styleValue.Render(fmt.Sprintf("%d", entries)) + "\n")
+	renderTLS(&b)
 
 	return styleBanner.Render(b.String())
 }
 
+// renderTLS adds the tls line to a banner: where the certificate comes from,
+// and whether client certificates are required
+func renderTLS(b *strings.Builder) {
+	if !tlsOpts.active() {
+		return
+	}
+	source := tlsOpts.Cert
+	if tlsCAPath != "" {
+		source = "trust " + tlsCAPath
+	}
+	mode := "HTTP/2"
+	if tlsOpts.MTLS {
+		mode += " + mTLS"
+	}
+	b.WriteString(styleLabel.Render("tls") + styleValue.Render(mode) + " " + lipgloss.NewStyle().Foreground(colorDim).Render(source) + "\n")
+}
+
+// renderSpecNotes lists what converting the spec changed, under the config
+func renderSpecNotes(b *strings.Builder) {
+	for i, note := range specNotes {
+		label := ""
+		if i == 0 {
+			label = "notes"
+		}
+		b.WriteString(styleLabel.Render(label) + lipgloss.NewStyle().Foreground(colorOrange).Render(note) + "\n")
+	}
+}
+
 // renderRoutes renders the route list
 func renderRoutes(routes []routeInfo) string {
 	var b strings.Builder
@@ -182,9 +226,14 @@ func renderRoutes(routes []routeInfo) string {
 	return b.String()
 }
 
+// renderMount renders the heading for one spec's routes when serving several
+func renderMount(name, url string) string {
+	return "\n  " + lipgloss.NewStyle().Bold(true).Render(name) + " " + styleURL.Render(url) + "\n"
+}
+
 // renderReady renders the ready message
 func renderReady(portNum int) string {
-	url := fmt.Sprintf("http://localhost:%d", portNum)
+	url := fmt.Sprintf("%s://localhost:%d", scheme(), portNum)
 	return lipgloss.NewStyle().MarginLeft(1).MarginBottom(1).Render(
 		lipgloss.NewStyle().Foreground(colorGreen).Render("● ") +
 			lipgloss.NewStyle().Foreground(colorMuted).Render("ready at ") +
@@ -256,6 +305,7 @@ func renderDiffBanner(specFile, target string) string {
 	b.WriteString(title + "\n")
 	b.WriteString(styleLabel.Render("spec") + styleValue.Render(specFile) + "\n")
 	b.WriteString(styleLabel.Render("target") + styleURL.Render(target) + "\n")
+	renderSpecNotes(&b)
 	return styleBanner.Render(b.String())
 }
 
@@ -307,6 +357,78 @@ func logWebhookDelivery(event, target string, status int, err error) {
 	}
 }
 
+// logGraphQL logs a GraphQL operation and how many errors it returned
+func logGraphQL(operation string, status int, errs int, dur time.Duration) {
+	label, name, _ := strings.Cut(operation, " ")
+	m := methodStyle("QUERY").Render("QUERY")
+	if label == "mutation" {
+		m = methodStyle("MUTATE").Render("MUTATE")
+	}
+	p := stylePath.Render(name)
+	s := statusStyle(status)
+	t := timingStyle(dur)
+	if errs > 0 {
+		label := "errors"
+		if errs == 1 {
+			label = "error"
+		}
+		e := lipgloss.NewStyle().Foreground(colorYellow).Render(fmt.Sprintf("%d %s", errs, label))
+		fmt.Printf("  %s %s %s %s %s\n", m, p, s, e, t)
+		return
+	}
+	fmt.Printf("  %s %s %s %s\n", m, p, s, t)
+}
+
+// logGRPC logs an RPC with its status code
+func logGRPC(method string, code codes.Code, dur time.Duration) {
+	m := methodStyle("GRPC").Render("GRPC")
+	p := stylePath.Render(method)
+	color := colorGreen
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unknown:
+		color = colorRed
+	default:
+		color = colorYellow
+	}
+	c := lipgloss.NewStyle().Foreground(color).Render(code.String())
+	t := timingStyle(dur)
+	fmt.Printf("  %s %s %s %s\n", m, p, c, t)
+}
+
+// logSOAP logs a SOAP operation, with the fault it returned if any
+func logSOAP(operation string, status int, fault string, dur time.Duration) {
+	m := methodStyle("SOAP").Render("SOAP")
+	p := stylePath.Render(operation)
+	s := statusStyle(status)
+	t := timingStyle(dur)
+	if fault != "" {
+		f := lipgloss.NewStyle().Foreground(colorYellow).Render("fault " + fault)
+		fmt.Printf("  %s %s %s %s %s\n", m, p, s, f, t)
+		return
+	}
+	fmt.Printf("  %s %s %s %s\n", m, p, s, t)
+}
+
+// logWebSocket logs activity on an open WebSocket connection
+func logWebSocket(path, action, message string) {
+	m := methodStyle("WS").Render("WS")
+	p := stylePath.Render(path)
+	var a string
+	switch action {
+	case "sent":
+		a = lipgloss.NewStyle().Foreground(colorGreen).Render("→")
+	case "received":
+		a = lipgloss.NewStyle().Foreground(colorBlue).Render("←")
+	case "invalid", "rejected":
+		a = lipgloss.NewStyle().Foreground(colorYellow).Render("✗ " + action)
+	default:
+		a = lipgloss.NewStyle().Foreground(colorDim).Render(action)
+	}
+	msg := lipgloss.NewStyle().Foreground(colorMuted).Render(message)
+	fmt.Printf("  %s %s %s %s\n", m, p, a, msg)
+}
+
 // logStrictWarning logs a strict mode warning
 func logStrictWarning(context, msg string) {
 	warn := lipgloss.NewStyle().Foreground(colorYellow).Bold(true).Render("⚠ strict")
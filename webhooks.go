@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -45,6 +46,25 @@ func (wm *WebhookManager) FireWebhook(method, path string, statusCode int, respo
 
 	// build webhook payload
 	event := inferEventName(method, path)
+	var payload interface{}
+	if name, item := wm.findWebhook(method, path); item != nil {
+		// the spec declares this webhook, so send what it describes
+		event = name
+		payload = webhookPayload(item, name, responseBody)
+	}
+	if payload == nil {
+		payload = wm.defaultPayload(method, path, statusCode, event, responseBody)
+	}
+
+	go func() {
+		if wm.delay > 0 {
+			time.Sleep(wm.delay)
+		}
+		wm.deliverWithRetry(payload, event)
+	}()
+}
+
+func (wm *WebhookManager) defaultPayload(method, path string, statusCode int, event string, responseBody interface{}) map[string]interface{} {
 	payload := map[string]interface{}{
 		"event":     event,
 		"method":    method,
@@ -68,13 +88,59 @@ func (wm *WebhookManager) FireWebhook(method, path string, statusCode int, respo
 			}
 		}
 	}
+	return payload
+}
 
-	go func() {
-		if wm.delay > 0 {
-			time.Sleep(wm.delay)
+// findWebhook picks the spec's 3.1 webhook for a change, preferring one whose
+// name also says what happened
+func (wm *WebhookManager) findWebhook(method, path string) (string, *openapi3.PathItem) {
+	webhooks := specWebhooks(wm.doc)
+	names := make([]string, 0, len(webhooks))
+	for name := range webhooks {
+		if matchesWebhook(name, method, path) {
+			names = append(names, name)
 		}
-		wm.deliverWithRetry(payload, event)
-	}()
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if webhookAction(name) != "" {
+			return name, webhooks[name]
+		}
+	}
+	return names[0], webhooks[names[0]]
+}
+
+// webhookPayload generates the webhook's request body, carrying over the
+// fields of the changed resource that it declares
+func webhookPayload(item *openapi3.PathItem, name string, responseBody interface{}) interface{} {
+	op := getWebhookOperation(item)
+	if op == nil || op.RequestBody == nil || op.RequestBody.Value == nil {
+		return nil
+	}
+	media := op.RequestBody.Value.Content.Get("application/json")
+	if media == nil || media.Schema == nil {
+		return nil
+	}
+	payload := generateFromSchema(media.Schema, seededRng(seed, name), 0)
+	m, ok := payload.(map[string]interface{})
+	if !ok || media.Schema.Value == nil {
+		return payload
+	}
+	props := media.Schema.Value.Properties
+	if body, ok := responseBody.(map[string]interface{}); ok {
+		for k, v := range body {
+			if _, declared := props[k]; declared {
+				m[k] = v
+			}
+		}
+	}
+	if _, declared := props["data"]; declared && responseBody != nil {
+		m["data"] = responseBody
+	}
+	return m
 }
 
 func (wm *WebhookManager) deliverWithRetry(payload interface{}, event string) {
@@ -134,24 +200,34 @@ func inferEventName(method, path string) string {
 	}
 }
 
+// matchesWebhook reports whether a webhook is about a change: its name has to
+// mention the resource, and any action it names has to fit the method
 func matchesWebhook(webhookName, method, path string) bool {
 	name := strings.ToLower(webhookName)
-	resource := strings.ToLower(extractResource(path))
-	m := strings.ToLower(method)
-
-	if strings.Contains(name, resource) {
-		return true
+	resource := singular(strings.ToLower(extractResource(path)))
+	if resource == "" || !strings.Contains(name, resource) {
+		return false
 	}
-	if m == "post" && (strings.Contains(name, "create") || strings.Contains(name, "new")) {
-		return true
-	}
-	if (m == "put" || m == "patch") && strings.Contains(name, "update") {
-		return true
-	}
-	if m == "delete" && strings.Contains(name, "delete") {
-		return true
+	action := webhookAction(webhookName)
+	return action == "" || action == strings.ToUpper(method) || (action == "PUT" && strings.EqualFold(method, "PATCH"))
+}
+
+// webhookActions are the words in a webhook's name that say what happened
+var webhookActions = map[string]string{
+	"create": "POST", "creates": "POST", "created": "POST", "creation": "POST", "new": "POST", "add": "POST", "adds": "POST", "added": "POST",
+	"update": "PUT", "updates": "PUT", "updated": "PUT", "change": "PUT", "changes": "PUT", "changed": "PUT", "edit": "PUT", "edits": "PUT", "edited": "PUT",
+	"delete": "DELETE", "deletes": "DELETE", "deleted": "DELETE", "deletion": "DELETE", "remove": "DELETE", "removes": "DELETE", "removed": "DELETE",
+}
+
+// webhookAction is the method a webhook's name implies, "" when it names none.
+// whole words only, so address.updated is an update and renewal isn't new
+func webhookAction(name string) string {
+	for _, tok := range nameTokens(name) {
+		if action, ok := webhookActions[tok]; ok {
+			return action
+		}
 	}
-	return false
+	return ""
 }
 
 func getWebhookOperation(pathItem *openapi3.PathItem) *openapi3.Operation {
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestWebhookAction(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"pet.created", "POST"},
+		{"petCreated", "POST"},
+		{"new-order", "POST"},
+		{"item_added", "POST"},
+		{"address.updated", "PUT"},
+		{"renewal.updated", "PUT"},
+		{"subscription.renewal", ""},
+		{"UserChanged", "PUT"},
+		{"invoice.deleted", "DELETE"},
+		{"member_removed", "DELETE"},
+		{"newsletter.sent", ""},
+		{"addon.synced", ""},
+	}
+	for _, tt := range tests {
+		if got := webhookAction(tt.name); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesWebhook(t *testing.T) {
+	tests := []struct {
+		name, method, path string
+		want               bool
+	}{
+		{"address.updated", "PUT", "/addresses/1", true},
+		{"address.updated", "PATCH", "/addresses/1", true},
+		{"address.updated", "POST", "/addresses", false},
+		{"categoryCreated", "POST", "/categories", true},
+		{"renewal.updated", "POST", "/renewals", false},
+		{"renewal.updated", "PUT", "/renewals/7", true},
+		{"pet.deleted", "DELETE", "/pets/1", true},
+		{"pet.deleted", "DELETE", "/orders/1", false},
+	}
+	for _, tt := range tests {
+		if got := matchesWebhook(tt.name, tt.method, tt.path); got != tt.want {
+			t.Errorf("%s on %s %s: got %v, want %v", tt.name, tt.method, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gorilla/websocket"
+)
+
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || cors.originAllowed(origin)
+	},
+}
+
+// boundMessage is an outbound message published when a REST resource changes
+type boundMessage struct {
+	msg      *asyncMessage
+	resource string
+	kind     string // created, updated, deleted or "" for any change
+}
+
+// wsSession is one client connection to a channel
+type wsSession struct {
+	conn      *websocket.Conn
+	writeMu   sync.Mutex
+	channel   *asyncChannel
+	path      string
+	seed      int64
+	interval  time.Duration
+	scheduled []*asyncMessage
+	bound     []boundMessage
+	store     *Store
+	done      <-chan struct{}
+	sent      int
+}
+
+// serveWebSocket upgrades a request for an AsyncAPI channel and runs the
+// session until either side closes it or the server shuts down
+func (s *MockServer) serveWebSocket(w http.ResponseWriter, r *http.Request, ch *asyncChannel) {
+	start := time.Now()
+	ws := &wsSession{
+		channel:  ch,
+		path:     r.URL.Path,
+		seed:     s.seed,
+		interval: wsInterval,
+		store:    s.store,
+		done:     s.done,
+	}
+	if ch.interval > 0 {
+		ws.interval = ch.interval
+	}
+	if v := parsePreference(r, "interval"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			ws.interval = d
+		}
+	}
+
+	// messages named after a CRUD collection follow it instead of the timer
+	s.mu.RLock()
+	bound := map[*asyncMessage]bool{}
+	for _, msg := range ch.outbound {
+		if resource, kind := s.boundResource(ch.address, msg.name); resource != "" {
+			ws.bound = append(ws.bound, boundMessage{msg: msg, resource: resource, kind: kind})
+			bound[msg] = true
+		}
+	}
+	s.mu.RUnlock()
+	for _, msg := range ch.scheduled {
+		if !bound[msg] {
+			ws.scheduled = append(ws.scheduled, msg)
+		}
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already written the error response
+		logRequest("WS", r.URL.Path, 400, time.Since(start))
+		return
+	}
+	ws.conn = conn
+	logRequest("WS", r.URL.Path, 101, time.Since(start))
+	ws.run()
+	logWebSocket(r.URL.Path, "closed", "")
+}
+
+func (ws *wsSession) run() {
+	defer ws.conn.Close()
+
+	inbound := make(chan []byte)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			_, data, err := ws.conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			select {
+			case inbound <- data:
+			case <-ws.done:
+				return
+			}
+		}
+	}()
+
+	var changes <-chan storeEvent
+	if len(ws.bound) > 0 {
+		c, unsubscribe := ws.store.Subscribe()
+		defer unsubscribe()
+		changes = c
+	}
+
+	var tick <-chan time.Time
+	if len(ws.scheduled) > 0 {
+		ticker := time.NewTicker(ws.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+		ws.publishScheduled()
+	}
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ws.done:
+			ws.writeMu.Lock()
+			ws.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+				time.Now().Add(time.Second))
+			ws.writeMu.Unlock()
+			return
+		case data := <-inbound:
+			ws.receive(data)
+		case <-tick:
+			ws.publishScheduled()
+		case change := <-changes:
+			for _, b := range ws.bound {
+				if b.resource == change.Resource && (b.kind == "" || b.kind == change.Kind) {
+					ws.send(b.msg, ws.fromStore(b, change))
+				}
+			}
+		}
+	}
+}
+
+// publishScheduled sends the next generated message. channels with several
+// messages rotate through them, seeded by position like SSE events
+func (ws *wsSession) publishScheduled() {
+	rng := seededRng(ws.seed, ws.path+"#ws"+strconv.Itoa(ws.sent+1))
+	msg := ws.scheduled[rng.Intn(len(ws.scheduled))]
+	ws.send(msg, generateMessage(msg, ws.seed, ws.path+"#ws"+strconv.Itoa(ws.sent+1)))
+}
+
+// receive validates an inbound frame against the channel's messages and
+// answers it with a reply, if the operation declares one
+func (ws *wsSession) receive(data []byte) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		value = string(data)
+	}
+
+	if len(ws.channel.inbound) == 0 {
+		ws.sendError("channel does not accept messages", nil)
+		logWebSocket(ws.path, "rejected", "channel is send-only")
+		return
+	}
+
+	var firstErr error
+	var matched *asyncMessage
+	for _, msg := range ws.channel.inbound {
+		if msg.schema == nil || msg.schema.Value == nil {
+			matched = msg
+			break
+		}
+		err := msg.schema.Value.VisitJSON(value, openapi3.MultiErrors())
+		if err == nil {
+			matched = msg
+			break
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if matched == nil {
+		ws.sendError("invalid message", parseValidationError(firstErr))
+		logWebSocket(ws.path, "invalid", ws.channel.inbound[0].name)
+		return
+	}
+	logWebSocket(ws.path, "received", matched.name)
+
+	if len(matched.replies) == 0 {
+		return
+	}
+	key := ws.path + "#reply" + strconv.Itoa(ws.sent+1)
+	rng := seededRng(ws.seed, key)
+	reply := matched.replies[rng.Intn(len(matched.replies))]
+	payload := generateMessage(reply, ws.seed, key)
+	correlate(payload, value)
+	ws.send(reply, payload)
+}
+
+// correlate copies the request's id fields onto the reply, so clients can
+// match them up
+func correlate(reply, request interface{}) {
+	out, ok := reply.(map[string]interface{})
+	if !ok {
+		return
+	}
+	in, ok := request.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for name, v := range in {
+		switch normalizeFieldName(name) {
+		case "id", "requestid", "correlationid", "messageid":
+			if _, declared := out[name]; declared {
+				out[name] = v
+			}
+		}
+	}
+}
+
+func (ws *wsSession) send(msg *asyncMessage, payload interface{}) {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	ws.sent++
+	if text, ok := payload.(string); ok {
+		ws.conn.WriteMessage(websocket.TextMessage, []byte(text))
+	} else {
+		ws.conn.WriteJSON(payload)
+	}
+	logWebSocket(ws.path, "sent", msg.name)
+}
+
+func (ws *wsSession) sendError(detail string, details []map[string]string) {
+	body := map[string]interface{}{"error": detail}
+	if len(details) > 0 {
+		body["details"] = details
+	}
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	ws.conn.WriteJSON(body)
+}
+
+// generateMessage builds a payload from the message schema, falling back to
+// its first example
+func generateMessage(msg *asyncMessage, seed int64, key string) interface{} {
+	if msg.schema != nil {
+		return generateFromSchema(msg.schema, seededRng(seed, key), 0)
+	}
+	if msg.example != nil {
+		return msg.example
+	}
+	return map[string]interface{}{}
+}
+
+// fromStore generates the message and fills it with the changed object. the
+// object goes in a data/payload/<resource> property when the message wraps
+// it, else onto the message itself
+func (ws *wsSession) fromStore(b boundMessage, change storeEvent) interface{} {
+	key := ws.path + "#" + change.Name() + "#" + change.ID
+	payload := generateMessage(b.msg, ws.seed, key)
+	m, ok := payload.(map[string]interface{})
+	stored, isMap := change.Data.(map[string]interface{})
+	if !ok || !isMap {
+		return change.Data
+	}
+
+	var target map[string]interface{}
+	var schema *openapi3.Schema
+	if b.msg.schema != nil {
+		schema = b.msg.schema.Value
+		// free-form event names get the webhook-style name, users.created
+		for _, name := range []string{"event", "type", "action"} {
+			if prop := schema.Properties[name]; prop != nil && prop.Value != nil && prop.Value.Type.Is("string") && len(prop.Value.Enum) == 0 {
+				m[name] = change.Name()
+			}
+		}
+	}
+	for _, name := range []string{"data", "payload", singular(b.resource), b.resource} {
+		if inner, ok := m[name].(map[string]interface{}); ok {
+			target = inner
+			if schema != nil && schema.Properties[name] != nil {
+				schema = schema.Properties[name].Value
+			}
+			break
+		}
+	}
+	if target == nil {
+		target = m
+	}
+	for k, v := range stored {
+		if _, declared := target[k]; declared || schema == nil || len(schema.Properties) == 0 {
+			target[k] = v
+		}
+	}
+	return m
+}
+
+// boundResource ties a message to a CRUD collection by name: a userCreated
+// message or a /users channel follows /users, created/updated/deleted in the
+// name narrows which changes it follows
+func (s *MockServer) boundResource(address, messageName string) (string, string) {
+	tokens := append(nameTokens(messageName), nameTokens(address)...)
+	resource, kind := "", ""
+	for _, tok := range tokens {
+		switch tok {
+		case "created", "create", "added", "new":
+			kind = "created"
+			continue
+		case "updated", "update", "changed", "modified":
+			kind = "updated"
+			continue
+		case "deleted", "delete", "removed":
+			kind = "deleted"
+			continue
+		}
+		if resource != "" {
+			continue
+		}
+		if s.isCollection(tok) {
+			resource = tok
+		} else if s.isCollection(tok + "s") {
+			resource = tok + "s"
+		}
+	}
+	if resource == "" {
+		return "", ""
+	}
+	return resource, kind
+}
+
+// singular undoes the usual plurals of resource names: categories, boxes, pets
+func singular(resource string) string {
+	switch {
+	case strings.HasSuffix(resource, "ies"):
+		return strings.TrimSuffix(resource, "ies") + "y"
+	case strings.HasSuffix(resource, "sses"), strings.HasSuffix(resource, "xes"), strings.HasSuffix(resource, "ches"), strings.HasSuffix(resource, "shes"):
+		return strings.TrimSuffix(resource, "es")
+	}
+	return strings.TrimSuffix(resource, "s")
+}
+
+// printChannels lists the WebSocket channels under the REST routes. they're
+// mounted with the first spec, so base is its prefix and base path
+func printChannels(api *AsyncAPI, base string) {
+	if api == nil || len(api.Channels) == 0 {
+		return
+	}
+	routes := make([]routeInfo, 0, len(api.Channels))
+	for _, ch := range api.Channels {
+		routes = append(routes, routeInfo{path: base + ch.address, methods: []string{"WS"}})
+	}
+	fmt.Print(renderRoutes(routes))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const pricesAsyncAPI = `asyncapi: 2.6.0
+info: {title: prices, version: "1"}
+channels:
+  /prices/{symbol}:
+    subscribe:
+      message:
+        payload: {type: object, properties: {price: {type: number}}}
+`
+
+const versionedSpec = `openapi: 3.0.3
+info: {title: shop, version: "1"}
+servers: [{url: https://api.example.com/v1}]
+paths:
+  /items:
+    get:
+      responses: {'200': {description: ok}}
+`
+
+// channels live under the same prefix and base path as the spec they're
+// mounted with
+func TestWebSocketChannelUnderMount(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"prices.yaml": pricesAsyncAPI, "api.yaml": versionedSpec} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	async, err := loadAsyncAPI(filepath.Join(dir, "prices.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { wsInterval = d }(wsInterval)
+	wsInterval = 10 * time.Millisecond
+
+	m := &specMount{file: filepath.Join(dir, "api.yaml"), prefix: "/shop"}
+	s, err := loadMockServer(m, "", async)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer close(s.done)
+	m.server = s
+	srv := httptest.NewServer(newSpecMux([]*specMount{m}, []*specMount{m}))
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"/shop/v1/prices/ACME", true},
+		{"/shop/prices/ACME", true}, // server base paths are optional
+		{"/prices/ACME", false},
+		{"/shop/v1/prices", false},
+	}
+	for _, tt := range tests {
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL+tt.path, nil)
+		if !tt.ok {
+			if err == nil {
+				conn.Close()
+				t.Errorf("%s: upgraded, want a 404", tt.path)
+			} else if resp == nil || resp.StatusCode != 404 {
+				t.Errorf("%s: %v, want a 404", tt.path, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Errorf("%s: reading a price: %v", tt.path, err)
+		} else if _, ok := msg["price"]; !ok {
+			t.Errorf("%s: got %v, want a price", tt.path, msg)
+		}
+		conn.Close()
+	}
+}
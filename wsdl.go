@@ -0,0 +1,636 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	nsWSDL   = "http://schemas.xmlsoap.org/wsdl/"
+	nsSOAP11 = "http://schemas.xmlsoap.org/wsdl/soap/"
+	nsSOAP12 = "http://schemas.xmlsoap.org/wsdl/soap12/"
+	nsXSD    = "http://www.w3.org/2001/XMLSchema"
+)
+
+// soapOperation is one operation of a SOAP binding. rpc-style operations get
+// a wrapper element named after the operation, so both styles look the same
+// from here on
+type soapOperation struct {
+	name    string
+	action  string
+	version string // "1.1" or "1.2"
+	path    string
+	input   *xsdElement
+	output  *xsdElement // nil for one-way operations
+	faults  map[string]*xsdElement
+}
+
+// xsdElement is an element declaration. max < 0 means unbounded; elements
+// from the same <choice> share a non-zero choice group
+type xsdElement struct {
+	name     xml.Name
+	typ      *xsdType
+	min, max int
+	choice   int
+}
+
+// xsdType is a compiled simple or complex type. simple types (and complex
+// types with simple content) have a builtin base
+type xsdType struct {
+	name     string
+	base     string // builtin type name: string, int, dateTime, ...
+	enum     []string
+	pattern  string
+	elements []*xsdElement
+	attrs    []*xsdAttr
+}
+
+type xsdAttr struct {
+	name     string
+	typ      *xsdType
+	required bool
+}
+
+func (t *xsdType) simple() bool {
+	return t.base != "" && len(t.elements) == 0 && len(t.attrs) == 0
+}
+
+// nsNode is a parsed element that, unlike xmlNode, keeps namespaces. ns holds
+// the prefixes in scope, which QName values like type="tns:User" need
+type nsNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []*nsNode  `xml:",any"`
+	Text     string     `xml:",chardata"`
+	ns       map[string]string
+}
+
+func parseNamespacedXML(data []byte) (*nsNode, error) {
+	var root nsNode
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	root.scope(map[string]string{"xml": "http://www.w3.org/XML/1998/namespace"})
+	return &root, nil
+}
+
+func (n *nsNode) scope(parent map[string]string) {
+	n.ns = parent
+	for _, a := range n.Attrs {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+			if len(n.ns) == len(parent) {
+				n.ns = make(map[string]string, len(parent)+1)
+				for k, v := range parent {
+					n.ns[k] = v
+				}
+			}
+			if a.Name.Space == "xmlns" {
+				n.ns[a.Name.Local] = a.Value
+			} else {
+				n.ns[""] = a.Value
+			}
+		}
+	}
+	for _, c := range n.Children {
+		c.scope(n.ns)
+	}
+}
+
+func (n *nsNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name && a.Name.Space == "" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// qname resolves a prefixed attribute value against the namespaces in scope
+func (n *nsNode) qname(value string) xml.Name {
+	prefix, local, ok := strings.Cut(value, ":")
+	if !ok {
+		return xml.Name{Space: n.ns[""], Local: value}
+	}
+	return xml.Name{Space: n.ns[prefix], Local: local}
+}
+
+func (n *nsNode) is(space, local string) bool {
+	return n.XMLName.Space == space && n.XMLName.Local == local
+}
+
+func (n *nsNode) child(space, local string) *nsNode {
+	for _, c := range n.Children {
+		if c.is(space, local) {
+			return c
+		}
+	}
+	return nil
+}
+
+// xsdSchema is what a schema's declarations inherit from it
+type xsdSchema struct {
+	tns       string
+	qualified bool // elementFormDefault="qualified"
+}
+
+// wsdlLoader collects the definitions and schemas of a WSDL and the files it
+// imports, then compiles the operations of every SOAP binding
+type wsdlLoader struct {
+	loaded   map[string]bool
+	schemaOf map[*nsNode]*xsdSchema
+	elements map[xml.Name]*nsNode
+	types    map[xml.Name]*nsNode
+	messages map[xml.Name]*nsNode
+	ports    map[xml.Name]*nsNode
+	bindings map[xml.Name]*nsNode
+	services []*nsNode
+
+	compiledElements map[xml.Name]*xsdElement
+	compiledTypes    map[xml.Name]*xsdType
+	choices          int
+}
+
+func loadWSDL(path string) ([]*soapOperation, error) {
+	l := &wsdlLoader{
+		loaded:           map[string]bool{},
+		schemaOf:         map[*nsNode]*xsdSchema{},
+		elements:         map[xml.Name]*nsNode{},
+		types:            map[xml.Name]*nsNode{},
+		messages:         map[xml.Name]*nsNode{},
+		ports:            map[xml.Name]*nsNode{},
+		bindings:         map[xml.Name]*nsNode{},
+		compiledElements: map[xml.Name]*xsdElement{},
+		compiledTypes:    map[xml.Name]*xsdType{},
+	}
+	if err := l.loadDefinitions(path); err != nil {
+		return nil, err
+	}
+	ops, err := l.operations()
+	if err != nil {
+		return nil, err
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("%s has no SOAP bindings", path)
+	}
+	return ops, nil
+}
+
+func (l *wsdlLoader) loadDefinitions(path string) error {
+	abs, _ := filepath.Abs(path)
+	if l.loaded[abs] {
+		return nil
+	}
+	l.loaded[abs] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	root, err := parseNamespacedXML(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if root.XMLName.Local == "description" {
+		return fmt.Errorf("%s is WSDL 2.0, only WSDL 1.1 is supported", path)
+	}
+	if !root.is(nsWSDL, "definitions") {
+		return fmt.Errorf("%s is not a WSDL 1.1 document", path)
+	}
+
+	tns := root.attr("targetNamespace")
+	dir := filepath.Dir(path)
+	for _, c := range root.Children {
+		if c.XMLName.Space != nsWSDL {
+			continue
+		}
+		name := xml.Name{Space: tns, Local: c.attr("name")}
+		switch c.XMLName.Local {
+		case "import":
+			if loc := c.attr("location"); loc != "" {
+				if err := l.loadDefinitions(filepath.Join(dir, loc)); err != nil {
+					return err
+				}
+			}
+		case "types":
+			for _, s := range c.Children {
+				if s.is(nsXSD, "schema") {
+					if err := l.addSchema(s, dir); err != nil {
+						return err
+					}
+				}
+			}
+		case "message":
+			l.messages[name] = c
+		case "portType":
+			l.ports[name] = c
+		case "binding":
+			l.bindings[name] = c
+		case "service":
+			l.services = append(l.services, c)
+		}
+	}
+	return nil
+}
+
+// addSchema indexes a schema's global elements and types, following
+// xsd:import and xsd:include with a local schemaLocation
+func (l *wsdlLoader) addSchema(s *nsNode, dir string) error {
+	schema := &xsdSchema{tns: s.attr("targetNamespace"), qualified: s.attr("elementFormDefault") == "qualified"}
+	for _, c := range s.Children {
+		if c.XMLName.Space != nsXSD {
+			continue
+		}
+		name := xml.Name{Space: schema.tns, Local: c.attr("name")}
+		switch c.XMLName.Local {
+		case "element":
+			l.elements[name] = c
+			l.schemaOf[c] = schema
+		case "complexType", "simpleType":
+			l.types[name] = c
+			l.schemaOf[c] = schema
+		case "group":
+			l.schemaOf[c] = schema
+		case "import", "include":
+			loc := c.attr("schemaLocation")
+			if loc == "" || strings.Contains(loc, "://") {
+				continue
+			}
+			file := filepath.Join(dir, loc)
+			abs, _ := filepath.Abs(file)
+			if l.loaded[abs] {
+				continue
+			}
+			l.loaded[abs] = true
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			root, err := parseNamespacedXML(data)
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", file, err)
+			}
+			if err := l.addSchema(root, filepath.Dir(file)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// operations compiles every operation of every SOAP port, at the path of its
+// address. bindings no service exposes are served at /
+func (l *wsdlLoader) operations() ([]*soapOperation, error) {
+	paths := map[xml.Name][]string{}
+	for _, svc := range l.services {
+		for _, port := range svc.Children {
+			if !port.is(nsWSDL, "port") {
+				continue
+			}
+			path := "/"
+			for _, addr := range port.Children {
+				if addr.XMLName.Local == "address" {
+					if u, err := url.Parse(addr.attr("location")); err == nil && u.Path != "" {
+						path = u.Path
+					}
+				}
+			}
+			binding := port.qname(port.attr("binding"))
+			paths[binding] = append(paths[binding], path)
+		}
+	}
+
+	names := make([]xml.Name, 0, len(l.bindings))
+	for name := range l.bindings {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].Local < names[j].Local })
+
+	var ops []*soapOperation
+	for _, name := range names {
+		binding := l.bindings[name]
+		version, style := "", "document"
+		for _, c := range binding.Children {
+			if c.XMLName.Local != "binding" {
+				continue
+			}
+			switch c.XMLName.Space {
+			case nsSOAP11:
+				version = "1.1"
+			case nsSOAP12:
+				version = "1.2"
+			}
+			if s := c.attr("style"); s != "" {
+				style = s
+			}
+		}
+		if version == "" {
+			continue // HTTP or MIME bindings
+		}
+		portType := l.ports[binding.qname(binding.attr("type"))]
+		if portType == nil {
+			return nil, fmt.Errorf("binding %s: unknown portType %s", name.Local, binding.attr("type"))
+		}
+		bindingPaths := paths[name]
+		if len(bindingPaths) == 0 {
+			bindingPaths = []string{"/"}
+		}
+		for _, bop := range binding.Children {
+			if !bop.is(nsWSDL, "operation") {
+				continue
+			}
+			op, err := l.operation(bop, portType, version, style)
+			if err != nil {
+				return nil, fmt.Errorf("operation %s: %w", bop.attr("name"), err)
+			}
+			for _, path := range bindingPaths {
+				o := *op
+				o.path = path
+				ops = append(ops, &o)
+			}
+		}
+	}
+	return ops, nil
+}
+
+func (l *wsdlLoader) operation(bop, portType *nsNode, version, style string) (*soapOperation, error) {
+	op := &soapOperation{name: bop.attr("name"), version: version, faults: map[string]*xsdElement{}}
+	var pop *nsNode
+	for _, c := range portType.Children {
+		if c.is(nsWSDL, "operation") && c.attr("name") == op.name {
+			pop = c
+		}
+	}
+	if pop == nil {
+		return nil, fmt.Errorf("not in portType %s", portType.attr("name"))
+	}
+
+	namespace := map[string]string{}
+	for _, c := range bop.Children {
+		if c.XMLName.Local == "operation" && (c.XMLName.Space == nsSOAP11 || c.XMLName.Space == nsSOAP12) {
+			op.action = c.attr("soapAction")
+			if s := c.attr("style"); s != "" {
+				style = s
+			}
+		}
+		if c.is(nsWSDL, "input") || c.is(nsWSDL, "output") {
+			for _, body := range c.Children {
+				if body.XMLName.Local == "body" {
+					namespace[c.XMLName.Local] = body.attr("namespace")
+				}
+			}
+		}
+	}
+
+	for _, c := range pop.Children {
+		if c.XMLName.Space != nsWSDL {
+			continue
+		}
+		msg := l.messages[c.qname(c.attr("message"))]
+		if msg == nil {
+			return nil, fmt.Errorf("unknown message %s", c.attr("message"))
+		}
+		switch c.XMLName.Local {
+		case "input":
+			op.input = l.body(msg, style, xml.Name{Space: namespace["input"], Local: op.name})
+		case "output":
+			op.output = l.body(msg, style, xml.Name{Space: namespace["output"], Local: op.name + "Response"})
+		case "fault":
+			op.faults[c.attr("name")] = l.body(msg, "document", xml.Name{})
+		}
+	}
+	if op.input == nil {
+		return nil, fmt.Errorf("no input message")
+	}
+	return op, nil
+}
+
+// body is the element a message puts in the soap:Body. document style uses
+// the first part's element; rpc style wraps each part in an element named
+// after the operation
+func (l *wsdlLoader) body(msg *nsNode, style string, wrapper xml.Name) *xsdElement {
+	var parts []*xsdElement
+	for _, p := range msg.Children {
+		if !p.is(nsWSDL, "part") {
+			continue
+		}
+		part := &xsdElement{min: 1, max: 1}
+		if el := p.attr("element"); el != "" {
+			part = l.globalElement(p.qname(el))
+		} else {
+			part.name = xml.Name{Local: p.attr("name")}
+			part.typ = l.namedType(p.qname(p.attr("type")))
+		}
+		parts = append(parts, part)
+	}
+	if style != "rpc" {
+		if len(parts) == 0 {
+			return nil
+		}
+		return parts[0]
+	}
+	return &xsdElement{name: wrapper, min: 1, max: 1, typ: &xsdType{name: wrapper.Local, elements: parts}}
+}
+
+func (l *wsdlLoader) globalElement(name xml.Name) *xsdElement {
+	if el, ok := l.compiledElements[name]; ok {
+		return el
+	}
+	node := l.elements[name]
+	if node == nil {
+		el := &xsdElement{name: name, min: 1, max: 1, typ: builtinType("string")}
+		l.compiledElements[name] = el
+		return el
+	}
+	el := &xsdElement{name: name, min: 1, max: 1}
+	l.compiledElements[name] = el
+	l.fillElement(el, node, l.schemaOf[node])
+	return el
+}
+
+func (l *wsdlLoader) fillElement(el *xsdElement, n *nsNode, schema *xsdSchema) {
+	if t := n.attr("type"); t != "" {
+		el.typ = l.namedType(n.qname(t))
+		return
+	}
+	for _, c := range n.Children {
+		if c.is(nsXSD, "complexType") || c.is(nsXSD, "simpleType") {
+			el.typ = l.compileType(c, el.name.Local, schema)
+			return
+		}
+	}
+	el.typ = builtinType("string") // xs:anyType
+}
+
+func (l *wsdlLoader) namedType(name xml.Name) *xsdType {
+	if name.Space == nsXSD {
+		return builtinType(name.Local)
+	}
+	if t, ok := l.compiledTypes[name]; ok {
+		return t
+	}
+	node := l.types[name]
+	if node == nil {
+		return builtinType("string")
+	}
+	t := &xsdType{name: name.Local}
+	// cache before compiling, so recursive types point back at themselves
+	l.compiledTypes[name] = t
+	*t = *l.compileType(node, name.Local, l.schemaOf[node])
+	return t
+}
+
+func builtinType(name string) *xsdType {
+	return &xsdType{name: name, base: name}
+}
+
+func (l *wsdlLoader) compileType(n *nsNode, name string, schema *xsdSchema) *xsdType {
+	t := &xsdType{name: name}
+	for _, c := range n.Children {
+		if c.XMLName.Space != nsXSD {
+			continue
+		}
+		switch c.XMLName.Local {
+		case "restriction":
+			l.restrict(t, c, schema)
+		case "list", "union":
+			t.base = "string"
+		case "sequence", "all", "choice", "group":
+			l.particles(t, c, schema, 0)
+		case "attribute":
+			t.attrs = append(t.attrs, l.attribute(c, schema))
+		case "complexContent", "simpleContent":
+			for _, d := range c.Children {
+				if !d.is(nsXSD, "extension") && !d.is(nsXSD, "restriction") {
+					continue
+				}
+				if b := d.attr("base"); b != "" {
+					base := l.namedType(d.qname(b))
+					t.base = base.base
+					t.enum = base.enum
+					t.pattern = base.pattern
+					if d.is(nsXSD, "extension") {
+						t.elements = append(t.elements, base.elements...)
+						t.attrs = append(t.attrs, base.attrs...)
+					}
+				}
+				if c.XMLName.Local == "simpleContent" && d.is(nsXSD, "restriction") {
+					l.restrict(t, d, schema)
+				}
+				sub := l.compileType(d, name, schema)
+				t.elements = append(t.elements, sub.elements...)
+				t.attrs = append(t.attrs, sub.attrs...)
+			}
+		}
+	}
+	if n.is(nsXSD, "simpleType") && t.base == "" {
+		t.base = "string"
+	}
+	return t
+}
+
+func (l *wsdlLoader) restrict(t *xsdType, n *nsNode, schema *xsdSchema) {
+	if b := n.attr("base"); b != "" {
+		base := l.namedType(n.qname(b))
+		t.base, t.enum, t.pattern = base.base, base.enum, base.pattern
+	}
+	var enum []string
+	for _, f := range n.Children {
+		switch {
+		case f.is(nsXSD, "enumeration"):
+			enum = append(enum, f.attr("value"))
+		case f.is(nsXSD, "pattern"):
+			t.pattern = f.attr("value")
+		case f.is(nsXSD, "simpleType"):
+			inner := l.compileType(f, t.name, schema)
+			t.base, t.enum, t.pattern = inner.base, inner.enum, inner.pattern
+		}
+	}
+	if len(enum) > 0 {
+		t.enum = enum
+	}
+}
+
+// particles flattens sequence/all/choice content into the type's elements
+func (l *wsdlLoader) particles(t *xsdType, n *nsNode, schema *xsdSchema, choice int) {
+	if n.is(nsXSD, "choice") {
+		l.choices++
+		choice = l.choices
+	}
+	if n.is(nsXSD, "group") {
+		if ref := n.attr("ref"); ref != "" {
+			if group := l.findGroup(n.qname(ref)); group != nil {
+				l.particles(t, group, l.schemaOf[group], choice)
+			}
+			return
+		}
+	}
+	for _, c := range n.Children {
+		if c.XMLName.Space != nsXSD {
+			continue
+		}
+		switch c.XMLName.Local {
+		case "element":
+			el := l.localElement(c, schema)
+			el.choice = choice
+			t.elements = append(t.elements, el)
+		case "sequence", "all", "choice", "group":
+			l.particles(t, c, schema, choice)
+		}
+	}
+}
+
+func (l *wsdlLoader) findGroup(name xml.Name) *nsNode {
+	for node, schema := range l.schemaOf {
+		if schema.tns == name.Space && node.is(nsXSD, "group") && node.attr("name") == name.Local {
+			return node
+		}
+	}
+	return nil
+}
+
+func (l *wsdlLoader) localElement(n *nsNode, schema *xsdSchema) *xsdElement {
+	el := &xsdElement{min: occurs(n.attr("minOccurs"), 1), max: occurs(n.attr("maxOccurs"), 1)}
+	if ref := n.attr("ref"); ref != "" {
+		global := l.globalElement(n.qname(ref))
+		el.name, el.typ = global.name, global.typ
+		return el
+	}
+	el.name = xml.Name{Local: n.attr("name")}
+	form := n.attr("form")
+	if form == "qualified" || (form == "" && schema != nil && schema.qualified) {
+		el.name.Space = schema.tns
+	}
+	l.fillElement(el, n, schema)
+	return el
+}
+
+func (l *wsdlLoader) attribute(n *nsNode, schema *xsdSchema) *xsdAttr {
+	a := &xsdAttr{name: n.attr("name"), required: n.attr("use") == "required"}
+	if ref := n.attr("ref"); ref != "" {
+		a.name = n.qname(ref).Local
+	}
+	if t := n.attr("type"); t != "" {
+		a.typ = l.namedType(n.qname(t))
+	} else if st := n.child(nsXSD, "simpleType"); st != nil {
+		a.typ = l.compileType(st, a.name, schema)
+	} else {
+		a.typ = builtinType("string")
+	}
+	return a
+}
+
+func occurs(v string, fallback int) int {
+	if v == "unbounded" {
+		return -1
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return n
+	}
+	return fallback
+}
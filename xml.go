@@ -0,0 +1,516 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/go-openapi/jsonpointer"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	openapi3filter.RegisterBodyDecoder("application/xml", xmlBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("text/xml", xmlBodyDecoder)
+}
+
+// --------------- Property Order ---------------
+
+// propertyOrder remembers the order properties are declared in the spec,
+// which kin-openapi's maps lose
+type propertyOrder map[*openapi3.Schema][]string
+
+// buildPropertyOrder walks the root spec document and records the declared
+// property order of every schema in it. schemas from other files fall back to
+// sorted order
+func buildPropertyOrder(doc *openapi3.T, src *specSource) propertyOrder {
+	if src == nil {
+		return nil
+	}
+	data := src.files[src.root]
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil || len(root.Content) == 0 {
+		return nil
+	}
+
+	order := propertyOrder{}
+	var walk func(node *yaml.Node, ptr string)
+	walk = func(node *yaml.Node, ptr string) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, val := node.Content[i], node.Content[i+1]
+				if key.Value == "properties" && val.Kind == yaml.MappingNode {
+					if schema := lookupSchema(doc, convertedPointer(ptr)); schema != nil {
+						names := make([]string, 0, len(val.Content)/2)
+						for j := 0; j+1 < len(val.Content); j += 2 {
+							names = append(names, val.Content[j].Value)
+						}
+						order[schema] = names
+					}
+				}
+				walk(val, ptr+"/"+escapePointerToken(key.Value))
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				walk(item, ptr+"/"+strconv.Itoa(i))
+			}
+		}
+	}
+	walk(root.Content[0], "")
+	return order
+}
+
+// convertedPointer maps Swagger 2.0 definitions to where conversion put them
+func convertedPointer(ptr string) string {
+	if rest, ok := strings.CutPrefix(ptr, "/definitions/"); ok {
+		return "/components/schemas/" + rest
+	}
+	return ptr
+}
+
+func escapePointerToken(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func lookupSchema(doc *openapi3.T, ptr string) *openapi3.Schema {
+	p, err := jsonpointer.New(ptr)
+	if err != nil {
+		return nil
+	}
+	v, _, err := p.Get(doc)
+	if err != nil {
+		return nil
+	}
+	switch s := v.(type) {
+	case *openapi3.Schema:
+		return s
+	case *openapi3.SchemaRef:
+		return s.Value
+	}
+	return nil
+}
+
+// orderedProperties lists a schema's properties in spec order when known
+func (o propertyOrder) orderedProperties(schema *openapi3.Schema) []string {
+	declared, ok := o[schema]
+	if !ok {
+		return sortedPropertyNames(schema)
+	}
+	names := make([]string, 0, len(schema.Properties))
+	seen := map[string]bool{}
+	for _, name := range declared {
+		if _, exists := schema.Properties[name]; exists && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+	for _, name := range sortedPropertyNames(schema) {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// --------------- XML Encoding ---------------
+
+// xmlEncoder serializes generated data following the schema's xml objects:
+// element names, attributes, wrapped arrays, namespaces and prefixes
+type xmlEncoder struct {
+	w     io.Writer
+	order propertyOrder
+}
+
+// writeXMLSchema writes data as an XML document. without a schema it falls
+// back to <root> and <item> elements
+func writeXMLSchema(w io.Writer, data interface{}, ref *openapi3.SchemaRef, order propertyOrder) {
+	w.Write([]byte(xml.Header))
+	enc := &xmlEncoder{w: w, order: order}
+
+	schema := schemaValue(ref)
+	rootName := "root"
+	if schema != nil && schema.XML != nil && schema.XML.Name != "" {
+		rootName = schema.XML.Name
+	} else if name := schemaName(ref); name != "" {
+		if _, isArray := data.([]interface{}); !isArray {
+			rootName = name
+		}
+	}
+
+	if arr, ok := data.([]interface{}); ok {
+		// a document needs a single root, so top-level arrays are always wrapped
+		var items *openapi3.SchemaRef
+		if schema != nil {
+			items = schema.Items
+		}
+		itemName := "item"
+		if is := schemaValue(items); is != nil && is.XML != nil && is.XML.Name != "" {
+			itemName = is.XML.Name
+		} else if name := schemaName(items); name != "" {
+			itemName = name
+		}
+		enc.open(rootName, schema, nil)
+		for _, item := range arr {
+			enc.element(itemName, items, item)
+		}
+		enc.close(rootName, schema)
+		return
+	}
+	enc.element(rootName, ref, data)
+}
+
+func schemaValue(ref *openapi3.SchemaRef) *openapi3.Schema {
+	if ref == nil {
+		return nil
+	}
+	return ref.Value
+}
+
+// qualifiedName applies the schema's xml name and prefix
+func qualifiedName(name string, schema *openapi3.Schema) string {
+	if schema == nil || schema.XML == nil {
+		return name
+	}
+	if schema.XML.Name != "" {
+		name = schema.XML.Name
+	}
+	if schema.XML.Prefix != "" {
+		name = schema.XML.Prefix + ":" + name
+	}
+	return name
+}
+
+func (e *xmlEncoder) open(name string, schema *openapi3.Schema, attrs []xml.Attr) {
+	fmt.Fprintf(e.w, "<%s", name)
+	if schema != nil && schema.XML != nil && schema.XML.Namespace != "" {
+		attr := "xmlns"
+		if schema.XML.Prefix != "" {
+			attr += ":" + schema.XML.Prefix
+		}
+		fmt.Fprintf(e.w, ` %s="%s"`, attr, escapeXML(schema.XML.Namespace))
+	}
+	for _, a := range attrs {
+		fmt.Fprintf(e.w, ` %s="%s"`, a.Name.Local, escapeXML(a.Value))
+	}
+	e.w.Write([]byte(">"))
+}
+
+func (e *xmlEncoder) close(name string, schema *openapi3.Schema) {
+	fmt.Fprintf(e.w, "</%s>", name)
+}
+
+// element writes one value. name is the property name; the schema may rename it
+func (e *xmlEncoder) element(name string, ref *openapi3.SchemaRef, value interface{}) {
+	schema := schemaValue(ref)
+	qname := qualifiedName(name, schema)
+
+	switch v := value.(type) {
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[k] = val
+		}
+		e.element(name, ref, m)
+	case map[string]interface{}:
+		e.object(qname, schema, v)
+	case []interface{}:
+		e.array(name, qname, schema, v)
+	case nil:
+		fmt.Fprintf(e.w, "<%s/>", qname)
+	default:
+		e.open(qname, schema, nil)
+		e.w.Write([]byte(escapeXML(fmt.Sprintf("%v", v))))
+		e.close(qname, schema)
+	}
+}
+
+func (e *xmlEncoder) object(qname string, schema *openapi3.Schema, m map[string]interface{}) {
+	var names []string
+	var attrs []xml.Attr
+	children := []string{}
+	if schema != nil {
+		names = e.order.orderedProperties(schema)
+	}
+	for _, name := range names {
+		val, ok := m[name]
+		if !ok {
+			continue
+		}
+		prop := schemaValue(schema.Properties[name])
+		if prop != nil && prop.XML != nil && prop.XML.Attribute {
+			attrs = append(attrs, xml.Attr{Name: xml.Name{Local: qualifiedName(name, prop)}, Value: fmt.Sprintf("%v", val)})
+			continue
+		}
+		children = append(children, name)
+	}
+
+	// keys the schema doesn't know about (stored POST bodies) go last, sorted
+	var extra []string
+	for k := range m {
+		if schema == nil || schema.Properties[k] == nil {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+
+	e.open(qname, schema, attrs)
+	for _, name := range children {
+		e.element(name, schema.Properties[name], m[name])
+	}
+	for _, name := range extra {
+		e.element(name, nil, m[name])
+	}
+	e.close(qname, schema)
+}
+
+// array writes list properties. wrapped arrays get an outer element named after
+// the property and inner elements named after the items; unwrapped arrays
+// repeat the item element directly. schemaless arrays keep the <item> layout
+func (e *xmlEncoder) array(name, qname string, schema *openapi3.Schema, arr []interface{}) {
+	var items *openapi3.SchemaRef
+	if schema != nil {
+		items = schema.Items
+	}
+	if schema == nil {
+		e.open(qname, nil, nil)
+		for _, item := range arr {
+			e.element("item", nil, item)
+		}
+		e.close(qname, nil)
+		return
+	}
+
+	if schema.XML != nil && schema.XML.Wrapped {
+		e.open(qname, schema, nil)
+		for _, item := range arr {
+			e.element(name, items, item)
+		}
+		e.close(qname, schema)
+		return
+	}
+	for _, item := range arr {
+		e.element(name, items, item)
+	}
+}
+
+func escapeXML(s string) string {
+	buf := &bytes.Buffer{}
+	xml.EscapeText(buf, []byte(s))
+	return buf.String()
+}
+
+// --------------- XML Decoding ---------------
+
+// xmlNode is a generic parsed element
+type xmlNode struct {
+	name     string
+	attrs    map[string]string
+	children []*xmlNode
+	text     string
+}
+
+func parseXML(r io.Reader) (*xmlNode, error) {
+	dec := xml.NewDecoder(r)
+	var stack []*xmlNode
+	var root *xmlNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name.Local, attrs: map[string]string{}}
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" {
+					continue
+				}
+				n.attrs[a.Name.Local] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("empty XML document")
+	}
+	return root, nil
+}
+
+// decodeXMLBody parses an XML request body into the same shape a JSON body
+// would have, using the schema's xml objects to map names and arrays
+func decodeXMLBody(data []byte, ref *openapi3.SchemaRef) (interface{}, error) {
+	root, err := parseXML(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	schema := schemaValue(ref)
+	if schema != nil && schema.Type.Is("array") {
+		return xmlArrayValue(root.children, schema.Items), nil
+	}
+	return xmlNodeValue(root, ref), nil
+}
+
+func xmlBodyDecoder(body io.Reader, _ http.Header, ref *openapi3.SchemaRef, _ openapi3filter.EncodingFn) (any, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeXMLBody(data, ref)
+}
+
+// xmlName is the element or attribute name a property is serialized under
+func xmlName(name string, schema *openapi3.Schema) string {
+	if schema != nil && schema.XML != nil && schema.XML.Name != "" {
+		return schema.XML.Name
+	}
+	return name
+}
+
+func xmlNodeValue(n *xmlNode, ref *openapi3.SchemaRef) interface{} {
+	schema := schemaValue(ref)
+	if schema == nil {
+		return xmlGuessValue(n)
+	}
+	if schema.Type.Is("array") {
+		return xmlArrayValue(n.children, schema.Items)
+	}
+	if !schema.Type.Is("object") && len(schema.Properties) == 0 {
+		return parseScalar(strings.TrimSpace(n.text), schema)
+	}
+
+	result := map[string]interface{}{}
+	claimed := map[*xmlNode]bool{}
+	for name, propRef := range schema.Properties {
+		prop := schemaValue(propRef)
+		elemName := xmlName(name, prop)
+
+		if prop != nil && prop.XML != nil && prop.XML.Attribute {
+			if v, ok := n.attrs[elemName]; ok {
+				result[name] = parseScalar(v, prop)
+			}
+			continue
+		}
+
+		var matches []*xmlNode
+		for _, c := range n.children {
+			if c.name == elemName {
+				matches = append(matches, c)
+			}
+		}
+
+		if prop != nil && prop.Type.Is("array") {
+			if prop.XML != nil && prop.XML.Wrapped {
+				if len(matches) > 0 {
+					result[name] = xmlArrayValue(matches[0].children, prop.Items)
+					claimed[matches[0]] = true
+				}
+				continue
+			}
+			// unwrapped arrays repeat the item element (or the property name)
+			itemName := xmlName(name, schemaValue(prop.Items))
+			var items []*xmlNode
+			for _, c := range n.children {
+				if c.name == itemName {
+					items = append(items, c)
+					claimed[c] = true
+				}
+			}
+			if len(items) > 0 {
+				result[name] = xmlArrayValue(items, prop.Items)
+			}
+			continue
+		}
+
+		if len(matches) > 0 {
+			result[name] = xmlNodeValue(matches[0], propRef)
+			claimed[matches[0]] = true
+		}
+	}
+
+	// keep unknown elements so stored resources don't silently lose data
+	for _, c := range n.children {
+		if claimed[c] {
+			continue
+		}
+		if _, exists := result[c.name]; !exists {
+			result[c.name] = xmlGuessValue(c)
+		}
+	}
+	return result
+}
+
+func xmlArrayValue(nodes []*xmlNode, items *openapi3.SchemaRef) []interface{} {
+	arr := make([]interface{}, 0, len(nodes))
+	for _, c := range nodes {
+		arr = append(arr, xmlNodeValue(c, items))
+	}
+	return arr
+}
+
+// xmlGuessValue converts an element without a schema: repeated children become arrays
+func xmlGuessValue(n *xmlNode) interface{} {
+	if len(n.children) == 0 && len(n.attrs) == 0 {
+		return strings.TrimSpace(n.text)
+	}
+	result := map[string]interface{}{}
+	for k, v := range n.attrs {
+		result[k] = v
+	}
+	counts := map[string]int{}
+	for _, c := range n.children {
+		counts[c.name]++
+	}
+	if len(counts) == 1 && len(n.children) > 1 && len(n.attrs) == 0 {
+		return xmlArrayValue(n.children, nil)
+	}
+	for _, c := range n.children {
+		if counts[c.name] > 1 {
+			arr, _ := result[c.name].([]interface{})
+			result[c.name] = append(arr, xmlGuessValue(c))
+			continue
+		}
+		result[c.name] = xmlGuessValue(c)
+	}
+	return result
+}
+
+// parseScalar converts text to the schema's type so XML and form bodies store like JSON ones
+func parseScalar(text string, schema *openapi3.Schema) interface{} {
+	switch {
+	case schema.Type.Is("integer"), schema.Type.Is("number"):
+		// float64, same as encoding/json
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	case schema.Type.Is("boolean"):
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+	}
+	return text
+}